@@ -0,0 +1,79 @@
+package ut
+
+import (
+	"context"
+	"time"
+)
+
+// actions are carried out when an expected call is made, before the mock
+// returns. They are run without the tracker lock held.
+type actions struct {
+	delay time.Duration
+	wait  <-chan struct{}
+	ctx   context.Context
+
+	panics     bool
+	panicValue any
+}
+
+func (a actions) run() {
+	if a.delay > 0 {
+		time.Sleep(a.delay)
+	}
+	if a.wait != nil {
+		<-a.wait
+	}
+	if a.ctx != nil {
+		<-a.ctx.Done()
+	}
+	if a.panics {
+		panic(a.panicValue)
+	}
+}
+
+// actionsFor builds the actions for a call to the named method that is
+// answered with r. declared is where the expectation was declared, if known.
+// The lock must be held.
+func (cr *callRecords) actionsFor(name, declared string, r *response, params []any) actions {
+	cr.t.Helper()
+	act := actions{
+		delay:      r.delay,
+		wait:       r.wait,
+		panics:     r.panics,
+		panicValue: r.panicValue,
+	}
+	if r.blockOnContext {
+		for _, p := range params {
+			if ctx, ok := p.(context.Context); ok {
+				act.ctx = ctx
+				break
+			}
+		}
+		if act.ctx == nil {
+			cr.logf("Call to %s%s should block until its context is done, but it has no context parameter", name, cr.formatter.FormatList(params))
+			cr.showLocations(declared)
+			cr.failNow("Call to %s has no context to wait for", name)
+		}
+	}
+	return act
+}
+
+func (cr *callRecords) WaitFor(ch <-chan struct{}) CallTracker {
+	cr.configure(nil, "WaitFor", func(e *Expectation) { e.resp.wait = ch })
+	return cr
+}
+
+func (cr *callRecords) Delay(d time.Duration) CallTracker {
+	cr.configure(nil, "Delay", func(e *Expectation) { e.resp.delay = d })
+	return cr
+}
+
+func (cr *callRecords) BlockUntilContextDone() CallTracker {
+	cr.configure(nil, "BlockUntilContextDone", func(e *Expectation) { e.resp.blockOnContext = true })
+	return cr
+}
+
+func (cr *callRecords) SetPanic(value any) CallTracker {
+	cr.configure(nil, "SetPanic", func(e *Expectation) { e.setPanic(value) })
+	return cr
+}
@@ -0,0 +1,161 @@
+package ut
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/philpearl/ut/uttest"
+)
+
+type MockSleeper struct {
+	CallTracker
+}
+
+func (m *MockSleeper) Sleep(ctx context.Context, d time.Duration) error {
+	r := m.TrackCall("Sleep", ctx, d)
+	return NilOrError(r[0])
+}
+
+func TestWaitFor(t *testing.T) {
+	m := NewMockReader(t)
+	release := make(chan struct{})
+	m.AddCall("Read", []byte("hat")).SetReturns(3, nil).WaitFor(release)
+
+	returned := make(chan int)
+	go func() {
+		n, _ := m.Read([]byte("hat"))
+		returned <- n
+	}()
+
+	// Once the call has been made it should block until we release it
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.WaitForCalls(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-returned:
+		t.Fatal("call should not return until released")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	if n := <-returned; n != 3 {
+		t.Fatalf("expected 3, have %d", n)
+	}
+	m.AssertDone()
+}
+
+func TestDelay(t *testing.T) {
+	m := NewMockReader(t)
+	m.AddCall("Read", []byte("hat")).SetReturns(3, nil).Delay(20 * time.Millisecond)
+
+	start := time.Now()
+	m.Read([]byte("hat"))
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("call returned after %s", elapsed)
+	}
+	m.AssertDone()
+}
+
+func TestBlockUntilContextDone(t *testing.T) {
+	m := &MockSleeper{NewCallRecords(t)}
+	m.AddCall("Sleep", func(actual any) {}, time.Hour).SetReturns(context.Canceled).BlockUntilContextDone()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	returned := make(chan error)
+	go func() {
+		returned <- m.Sleep(ctx, time.Hour)
+	}()
+
+	if err := m.WaitForCalls(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-returned:
+		t.Fatal("call should not return until the context is cancelled")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	cancel()
+	if err := <-returned; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, have %v", err)
+	}
+	m.AssertDone()
+}
+
+func TestBlockUntilContextDoneNoContext(t *testing.T) {
+	ft := uttest.ExpectFailure(t, func(tb testing.TB) {
+		m := &MockReader{NewCallRecords(tb)}
+		m.AddCall("Read", []byte("hat")).SetReturns(3, nil).BlockUntilContextDone()
+		m.TrackCall("Read", []byte("hat"))
+	})
+	if !ft.Stopped() {
+		t.Fatal("expected the test to stop")
+	}
+}
+
+func TestWaitForWrongMethod(t *testing.T) {
+	ft := uttest.NewTB(t)
+	m := &MockReader{NewCallRecords(ft)}
+	m.AddCall("Read", []byte("hat")).SetReturns(3, nil).WaitFor(make(chan struct{}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.TrackCall("Close")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the call to the wrong method waited for the expected call's channel")
+	}
+	if !ft.Failed() {
+		t.Fatal("expected the test to fail")
+	}
+}
+
+func TestSetPanicWrongMethod(t *testing.T) {
+	ft := uttest.NewTB(t)
+	m := &MockReader{NewCallRecords(ft)}
+	m.AddCall("Close").SetPanic("from Close")
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("the call to the wrong method panicked with %v", r)
+			}
+		}()
+		m.TrackCall("Read", []byte("hat"))
+	}()
+	if !ft.Failed() {
+		t.Fatal("expected the test to fail")
+	}
+	if calls := m.Calls(); len(calls) != 1 || calls[0].Panicked {
+		t.Fatalf("the call should not be recorded as panicking. %#v", calls)
+	}
+}
+
+func TestSetPanic(t *testing.T) {
+	m := NewMockReader(t)
+	m.AddCall("Read", []byte("hat")).SetPanic("oh no")
+
+	func() {
+		defer func() {
+			if r := recover(); r != "oh no" {
+				t.Fatalf("expected panic, recovered %v", r)
+			}
+		}()
+		m.Read([]byte("hat"))
+	}()
+	m.AssertDone()
+
+	tr, err := m.Trace(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Calls) != 1 || tr.Calls[0].Panic == nil || string(tr.Calls[0].Panic.Value) != `"oh no"` {
+		t.Fatalf("panic not in trace. %#v", tr.Calls)
+	}
+}
@@ -4,11 +4,17 @@ package ut
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"reflect"
+	"maps"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 // CallTracker is an interface to help build mocks.
@@ -39,13 +45,21 @@ import (
 //      m.AssertDone()
 //   }
 type CallTracker interface {
-	// AddCall() is used by tests to add an expected call to the tracker
+	// AddCall() is used by tests to add an expected call to the tracker. It
+	// returns a CallTracker on which the return values and behaviour of the
+	// call are set. This is an *Expectation, so the setters apply to this
+	// call even if others are added in the meantime.
 	AddCall(name string, params ...any) CallTracker
 
-	// SetReturns() is called immediately after AddCall() to set the return
-	// values for the call.
+	// SetReturns() sets the return values for the call. It is called on the
+	// CallTracker returned by AddCall() or RecordCall(). Called on the
+	// tracker itself it applies to the most recently added expectation.
 	SetReturns(returns ...any) CallTracker
 
+	// Times() is called on the CallTracker returned by AddCall() to expect
+	// n consecutive calls matching it rather than one.
+	Times(n int) CallTracker
+
 	// TrackCall() is called within mocks to track a call to the Mock. It
 	// returns the return values registered via SetReturns()
 	TrackCall(name string, params ...any) []any
@@ -54,6 +68,39 @@ type CallTracker interface {
 	// the expected calls have been made
 	AssertDone()
 
+	// Checkpoint() asserts that the expectations added so far have been met,
+	// as AssertDone() does, then discards them so the next phase of a long
+	// test can add its own. Recordings, defaults and the call history are
+	// kept.
+	Checkpoint()
+
+	// Reset() discards all expectations, recordings, defaults, injected
+	// faults and the call history without asserting anything, so the tracker
+	// can be reused, for example for each row of a table-driven test. The
+	// mode, signatures, formatter and any proxy are kept.
+	Reset() CallTracker
+
+	// Sub() creates a child tracker for the subtest t. The child reports
+	// failures to t and is verified with AssertDone() when the subtest ends.
+	// It starts with the parent's recordings, defaults and settings, but none
+	// of its expectations. Use it in a copy of the mock:
+	//
+	//	t.Run("sub", func(t *testing.T) {
+	//		m := &MockX{CallTracker: parent.Sub(t)}
+	//		m.AddCall("Get", "a").SetReturns("apple", nil)
+	//	})
+	Sub(t testing.TB) CallTracker
+
+	// AssertDoneWithin() is like AssertDone(), but first waits up to timeout
+	// for the expected calls to be made. Use it when the code under test
+	// calls the mock from background goroutines.
+	AssertDoneWithin(timeout time.Duration)
+
+	// WaitForCalls() blocks until all the expected calls have been made. If
+	// ctx is done first it returns an error describing the calls that are
+	// still outstanding.
+	WaitForCalls(ctx context.Context) error
+
 	// RecordCall() is called to indicate calls to the named mock method should
 	// be recorded rather than asserted.  The parameters to any call to the
 	// named method will be recorded and may be retrieved via GetRecordedParams.
@@ -66,30 +113,168 @@ type CallTracker interface {
 	// GetRecordedParams returns the sets of parameters passed to a call captured
 	// via RecordCall
 	GetRecordedParams(name string) ([][]any, bool)
+
+	// Trace() returns the expectations and the history of calls made to the
+	// tracker in a form that can be serialised as JSON. Parameter and return
+	// values are rendered with enc, or with DefaultValueEncoder if enc is nil.
+	Trace(enc ValueEncoder) (*Trace, error)
+
+	// Proxy() puts the tracker into recording proxy mode. Calls that are not
+	// captured via RecordCall are passed on to the matching method of real,
+	// and the parameters and returns are recorded in the call history rather
+	// than asserted. Only exported methods can be proxied. See Fixture.
+	Proxy(real any) CallTracker
+
+	// SetMode() selects how the tracker treats unexpected calls. The default
+	// is StrictMode.
+	SetMode(mode Mode) CallTracker
+
+	// SetDefault() sets the values returned by unexpected calls to the named
+	// method when the tracker is in LenientMode or RecordingMode. Without a
+	// default, unexpected calls return no values, which generated mocks
+	// convert to zero values.
+	SetDefault(name string, returns ...any) CallTracker
+
+	// WaitFor() is called immediately after AddCall() to make the call block
+	// until ch is closed or receives a value. Use this to orchestrate
+	// concurrent code under test deterministically.
+	WaitFor(ch <-chan struct{}) CallTracker
+
+	// Delay() is called immediately after AddCall() to make the call sleep
+	// for d before returning.
+	Delay(d time.Duration) CallTracker
+
+	// BlockUntilContextDone() is called immediately after AddCall() to make
+	// the call block until the context.Context passed to it is done. The
+	// first parameter that is a context.Context is used. Use this to test
+	// timeouts and cancellation.
+	BlockUntilContextDone() CallTracker
+
+	// SetPanic() is called immediately after AddCall() to make the call
+	// panic with value rather than return. Use this to test code that
+	// recovers from panics. The panic is recorded in the call history.
+	SetPanic(value any) CallTracker
+
+	// Handles() reports whether the tracker will handle a call to the named
+	// method: there is an outstanding expected call to it, it is captured via
+	// RecordCall, it has a default set via SetDefault, or the tracker is
+	// proxying or recording all calls. Mocks that delegate to another
+	// implementation use this to decide whether to track a call or pass it
+	// on.
+	Handles(name string) bool
+
+	// SetIgnoreContext() controls whether context.Context parameters may be
+	// left out of AddCall(). If set, and an expected call has fewer
+	// parameters than the actual call, any context.Context parameters of the
+	// actual call are ignored when comparing them.
+	SetIgnoreContext(ignore bool) CallTracker
+
+	// SetMethods() tells the tracker the signatures of the mock's methods.
+	// The tracker uses these to check the parameters passed to AddCall(), and
+	// the values passed to SetReturns(), RecordCall() and SetDefault(), as
+	// they are set, and to reject names that aren't methods of the mock.
+	// genmock generates the call to SetMethods().
+	SetMethods(methods ...Method) CallTracker
+
+	// SetFormatter() sets the Formatter used to show parameter and return
+	// values in failure messages. By default trackers use NewFormatter().
+	SetFormatter(f *Formatter) CallTracker
+
+	// Calls() returns the calls made to the mock so far, in order. If the
+	// mock shares a tracker with others, only its own calls are returned.
+	// Method names are as they appear in failure messages.
+	Calls() []Call
+
+	// Summary() returns a table of the expectations, how many calls each
+	// expects and how many matched, the parameters of the calls made
+	// against each, the calls captured via RecordCall() and any unexpected
+	// calls. Trackers log it as the test ends, by default only if the test
+	// failed. See WithSummary().
+	Summary() string
+
+	// FailNth() makes the nth matched call to the named method return err,
+	// counting from 1. The error replaces the method's error return value.
+	// This is found from the method's signature if it was passed to
+	// SetMethods(), otherwise it is taken to be the last return value that
+	// is nil or an error.
+	FailNth(name string, n int, err error) CallTracker
+
+	// FailEvery() makes every kth matched call to the named method return
+	// err, in the same way as FailNth().
+	FailEvery(name string, k int, err error) CallTracker
+
+	// FailWithProbability() makes matched calls to the named method return
+	// err with probability p, in the same way as FailNth(). The random
+	// sequence is seeded with seed so tests are repeatable.
+	FailWithProbability(name string, p float64, err error, seed int64) CallTracker
 }
 
-type callRecord struct {
-	name    string
-	params  []any
+// response describes how the mock responds to a call
+type response struct {
 	returns []any
+
+	// These configure how long the call blocks before returning
+	delay          time.Duration
+	wait           <-chan struct{}
+	blockOnContext bool
+	// If panics is set the call panics with panicValue rather than returning
+	panics     bool
+	panicValue any
+}
+
+type callRecord struct {
+	name   string
+	params []any
+	// mock and mockType identify the mock the expectation was added to
+	mock     string
+	mockType string
+	// declared is the location of the test code that added the expectation
+	declared string
+	response
+	// times is the number of calls expected. made counts the calls made, and
+	// matched those that had matching parameters
+	times   int
+	made    int
+	matched int
+	// passed is set once the expectation has been met by calls with
+	// matching parameters
+	passed bool
+	// history holds the indexes in the call history of the calls checked
+	// against the expectation
+	history []int
 }
 
-func (e *callRecord) assert(t testing.TB, name string, params ...any) {
+// outstanding reports whether more calls are expected
+func (e *callRecord) outstanding() bool {
+	return e.made < e.times
+}
+
+// assert checks the call against the expectation, and returns false if it
+// does not match
+func (e *callRecord) assert(cr *callRecords, name string, params ...any) bool {
+	t := cr.t
+	t.Helper()
 	if name != e.name {
-		t.Logf("Expected call to %s%s", e.name, paramsToString(e.params))
-		t.Logf(" got call to %s%s", name, paramsToString(params))
-		showStack(t)
+		cr.logf("Expected call to %s%s", e.name, cr.formatter.FormatList(e.params))
+		cr.logf(" got call to %s%s", name, cr.formatter.FormatList(params))
+		cr.showLocations(e.declared)
+		cr.showClosest(e, name, params)
 		t.Fail()
-		return
+		return false
+	}
+	if cr.ignoreContext && len(params) > len(e.params) {
+		params = withoutContexts(params)
 	}
 	if len(params) != len(e.params) {
-		t.Logf("Call to (%s) unexpected parameters", name)
-		t.Logf(" expected %s", paramsToString(e.params))
-		t.Logf("      got %s", paramsToString(params))
-		showStack(t)
-		t.FailNow()
-		return
+		cr.logf("Call to (%s) unexpected parameters", name)
+		cr.logf(" expected %s", cr.formatter.FormatList(e.params))
+		cr.logf("      got %s", cr.formatter.FormatList(params))
+		cr.showLocations(e.declared)
+		cr.showClosest(e, name, params)
+		cr.failNow("Call to %s has the wrong number of parameters", name)
+		return false
 	}
+	ok := true
 	for i, ap := range params {
 		ep := e.params[i]
 
@@ -100,123 +285,540 @@ func (e *callRecord) assert(t testing.TB, name string, params ...any) {
 		switch ep := ep.(type) {
 		case func(actual any):
 			ep(ap)
+		case Matcher:
+			if !ep.Match(ap) {
+				cr.logf("Call to %s parameter %d unexpected", name, i)
+				cr.logf("  expected %s", ep)
+				cr.logf("       got %s (%T)", cr.formatter.Format(ap), ap)
+				cr.showLocations(e.declared)
+				t.Fail()
+				ok = false
+			}
 		default:
-			if !reflect.DeepEqual(ap, ep) {
-				t.Logf("Call to %s parameter %d unexpected", name, i)
-				t.Logf("  expected %#v (%T)", ep, ep)
-				t.Logf("       got %#v (%T)", ap, ap)
-				showStack(t)
+			if !cr.equal(ep, ap) {
+				cr.logf("Call to %s parameter %d unexpected", name, i)
+				cr.logf("  expected %s (%T)", cr.formatter.Format(ep), ep)
+				cr.logf("       got %s (%T)", cr.formatter.Format(ap), ap)
+				if cr.cmpOptions != nil {
+					cr.logf("  diff (-expected +got):\n%s", cmp.Diff(ep, ap, cr.cmpOptions...))
+				}
+				cr.showLocations(e.declared)
 				t.Fail()
+				ok = false
 			}
 		}
 	}
-}
-
-func showStack(t testing.TB) {
-	pc := make([]uintptr, 10)
-	n := runtime.Callers(4, pc)
-	for i := 0; i < n; i++ {
-		f := runtime.FuncForPC(pc[i])
-		file, line := f.FileLine(pc[i])
-		t.Logf("  %s (%s line %d", f.Name(), file, line)
-	}
-}
-
-func paramsToString(params []any) string {
-	w := &bytes.Buffer{}
-	w.WriteString("(")
-	l := len(params)
-	for i, p := range params {
-		fmt.Fprintf(w, "%#v", p)
-		if i < l-1 {
-			w.WriteString(", ")
-		}
+	if !ok {
+		cr.showClosest(e, name, params)
 	}
-	w.WriteString(")")
-	return w.String()
+	return ok
 }
 
 // recording tracks calls actually made to the mock. It is used only when the
 // user choses to record calls for a method rather than assert them
 type recording struct {
-	// The returned values are the same for each call to a recorded method.
-	returns []any
+	// The response is the same for each call to a recorded method.
+	response
 	// We record the parameters from each call to the method.
 	params [][]any
 }
 
+// Mode controls how a CallTracker treats unexpected calls
+type Mode int
+
+const (
+	// StrictMode fails the test immediately if an unexpected call is made.
+	StrictMode Mode = iota
+	// LenientMode logs unexpected calls and returns default values for them.
+	// A call is unexpected if it is not to the method of the next expected
+	// call, or with Unordered() if no outstanding expected call is to the
+	// method.
+	LenientMode
+	// RecordingMode quietly records unexpected calls and returns default
+	// values for them, so a test can let the calls happen and check them
+	// afterwards with Verify.
+	RecordingMode
+)
+
+// callRecords is the CallTracker implementation. The state of the tracker
+// may be shared by the callRecords of several mocks, so that their expected
+// calls are checked in a single sequence. Each then prefixes the method names
+// it is given with the name of its mock.
 type callRecords struct {
+	*trackerState
+	// name is the name of the mock, and mockType its type, if known
+	name     string
+	mockType string
+	// prefix is added to method names to identify the mock in a shared
+	// tracker
+	prefix string
+	// proxy, if set, is the real implementation calls are passed on to
+	proxy any
+	// ignoreContext allows context.Context parameters to be left out of
+	// expected calls
+	ignoreContext bool
+}
+
+type trackerState struct {
 	sync.Mutex
+	// cond is signalled whenever a call is tracked
+	cond    *sync.Cond
 	t       testing.TB
-	calls   []callRecord
+	calls   []*callRecord
 	records map[string]*recording
 	current int
+	// last is the most recently added expectation, which setters called on
+	// the tracker itself apply to
+	last *Expectation
+	// history is every call made to the tracker, in the order they were made
+	history []Call
+	// unexpected holds the indexes in the history of calls that were not
+	// checked against any expectation
+	unexpected []int
+	// summary controls when a summary is logged as the test ends
+	summary SummaryMode
+	mode    Mode
+	// unordered allows the expected calls to be made in any order
+	unordered bool
+	// cmpOptions, if set, are used to compare parameters with cmp.Equal
+	// rather than reflect.DeepEqual
+	cmpOptions []cmp.Option
+	// defaults are the returns for unexpected calls in LenientMode
+	defaults map[string][]any
+	// methods are the signatures of the mock's methods, if known
+	methods map[string]Method
+	// formatter renders values in failure messages
+	formatter *Formatter
+	// faults are errors to inject into the returns of matched calls, and
+	// matchCounts counts the matched calls to each method
+	faults      map[string][]fault
+	matchCounts map[string]int
+	// goid identifies the goroutine running the test. Only this goroutine
+	// may call t.FailNow
+	goid uint64
+	// fatal records a failure that should have stopped the test but
+	// happened on another goroutine. It is reported again by AssertDone or
+	// when the test ends
+	fatal         string
+	fatalReported bool
 }
 
-// NewCallRecords creates a new call tracker
-func NewCallRecords(t testing.TB) CallTracker {
-	return &callRecords{
-		t:       t,
-		records: make(map[string]*recording),
+// NewCallRecords creates a new call tracker configured by opts. It should be
+// called from the goroutine running the test.
+func NewCallRecords(t testing.TB, opts ...Option) CallTracker {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.name == "" {
+		o.name = o.mockType
+	}
+	cr := &callRecords{name: o.name, mockType: o.mockType}
+	if o.tracker != nil {
+		shared, ok := trackerOf(o.tracker)
+		if !ok {
+			t.Fatalf("WithTracker: %T does not contain a tracker created by NewCallRecords", o.tracker)
+		}
+		cr.trackerState = shared.trackerState
+		if o.name != "" {
+			cr.prefix = o.name + "."
+		}
+	} else {
+		cr.trackerState = &trackerState{
+			t:           t,
+			records:     make(map[string]*recording),
+			defaults:    make(map[string][]any),
+			faults:      make(map[string][]fault),
+			matchCounts: make(map[string]int),
+			formatter:   NewFormatter(),
+			goid:        goid(),
+		}
+		cr.cond = sync.NewCond(&cr.Mutex)
+		// Cleanups run last first, so the summary knows whether the test
+		// failed
+		t.Cleanup(cr.summarise)
+		t.Cleanup(cr.reportFatal)
+	}
+
+	cr.Lock()
+	defer cr.Unlock()
+	for _, configure := range o.configure {
+		configure(cr)
 	}
+	return cr
+}
+
+// qualify returns the name of the method in the tracker's state
+func (cr *callRecords) qualify(name string) string {
+	return cr.prefix + name
 }
 
 func (cr *callRecords) AddCall(name string, params ...any) CallTracker {
-	cr.calls = append(cr.calls, callRecord{name: name, params: params})
-	return cr
+	name = cr.qualify(name)
+	cr.Lock()
+	defer cr.Unlock()
+	cr.checkParams(name, params)
+	call := &callRecord{name: name, params: params, mock: cr.name, mockType: cr.mockType, declared: callerLocation(), times: 1}
+	cr.calls = append(cr.calls, call)
+	cr.last = &Expectation{CallTracker: cr, cr: cr, name: name, resp: &call.response, call: call}
+	return cr.last
 }
 
 func (cr *callRecords) RecordCall(name string, returns ...any) CallTracker {
-	cr.records[name] = &recording{
-		returns: returns,
-		params:  make([][]any, 0),
+	name = cr.qualify(name)
+	cr.Lock()
+	defer cr.Unlock()
+	cr.checkReturns(name, returns)
+	record := &recording{
+		response: response{returns: returns},
+		params:   make([][]any, 0),
 	}
+	cr.records[name] = record
+	cr.last = &Expectation{CallTracker: cr, cr: cr, name: name, resp: &record.response}
+	return cr.last
+}
+
+func (cr *callRecords) SetMode(mode Mode) CallTracker {
+	cr.Lock()
+	defer cr.Unlock()
+	cr.mode = mode
+	return cr
+}
+
+func (cr *callRecords) SetIgnoreContext(ignore bool) CallTracker {
+	cr.Lock()
+	defer cr.Unlock()
+	cr.ignoreContext = ignore
+	return cr
+}
+
+func (cr *callRecords) SetFormatter(f *Formatter) CallTracker {
+	if f == nil {
+		f = NewFormatter()
+	}
+	cr.Lock()
+	defer cr.Unlock()
+	cr.formatter = f
+	return cr
+}
+
+func (cr *callRecords) SetDefault(name string, returns ...any) CallTracker {
+	name = cr.qualify(name)
+	cr.Lock()
+	defer cr.Unlock()
+	cr.checkReturns(name, returns)
+	cr.defaults[name] = returns
 	return cr
 }
 
 func (cr *callRecords) SetReturns(returns ...any) CallTracker {
-	cr.calls[len(cr.calls)-1].returns = returns
+	cr.configure(nil, "SetReturns", func(e *Expectation) { e.setReturns(returns) })
+	return cr
+}
+
+func (cr *callRecords) Times(n int) CallTracker {
+	cr.configure(nil, "Times", func(e *Expectation) { e.setTimes(n) })
 	return cr
 }
 
 func (cr *callRecords) TrackCall(name string, params ...any) []any {
+	cr.t.Helper()
+	returns, act := cr.trackCall(cr.qualify(name), params)
+	// Any blocking is done without the lock held so the test can carry on
+	// interacting with the tracker
+	act.run()
+	return returns
+}
+
+// trackCall matches the call against the expectations. It returns the values
+// the call should return, and any actions to carry out before returning.
+func (cr *callRecords) trackCall(name string, params []any) ([]any, actions) {
+	cr.t.Helper()
 	cr.Lock()
 	defer cr.Unlock()
+	defer cr.cond.Broadcast()
 	if record, ok := cr.records[name]; ok {
 		// Call is to be recorded, not asserted
 		record.params = append(record.params, params)
-		return record.returns
+		if record.panics {
+			cr.addHistory(Call{Name: name, Params: params, Passed: true, Panicked: true, Panic: record.panicValue})
+			return nil, cr.actionsFor(name, "", &record.response, params)
+		}
+		returns := cr.injectFaults(name, record.returns)
+		cr.addHistory(Call{Name: name, Params: params, Returns: returns, Passed: true})
+		return returns, cr.actionsFor(name, "", &record.response, params)
+	}
+	if cr.proxy != nil {
+		// The lock is released while the real implementation runs, so we
+		// reserve the call's place in the history first. The real method
+		// may change slice parameters, such as the buffer passed to Read,
+		// so we record copies.
+		i := len(cr.history)
+		cr.addHistory(Call{Name: name, Params: copySlices(params), Passed: true})
+		returns := cr.proxyCall(strings.TrimPrefix(name, cr.prefix), params)
+		if i < len(cr.history) && cr.history[i].Name == name {
+			// The history may have been discarded by Reset in the meantime
+			cr.history[i].Returns = returns
+		}
+		return returns, actions{}
+	}
+	expectedCall := cr.nextCall(name, params)
+	if cr.mode == RecordingMode && (expectedCall == nil || expectedCall.name != name) {
+		// The call is checked afterwards, if at all
+		returns := cr.defaults[name]
+		cr.addHistory(Call{Name: name, Params: params, Returns: returns, Passed: true})
+		return returns, actions{}
+	}
+	if cr.mode == LenientMode && (expectedCall == nil || expectedCall.name != name) {
+		returns := cr.defaults[name]
+		cr.unexpected = append(cr.unexpected, len(cr.history))
+		cr.addHistory(Call{Name: name, Params: params, Returns: returns})
+		cr.logf("Unexpected call to %s%s returns defaults %s", name, cr.formatter.FormatList(params), cr.formatter.FormatList(returns))
+		return returns, actions{}
 	}
 	// Call is to be asserted
-	if cr.current >= len(cr.calls) {
-		cr.t.Logf("Unexpected call to %s%s", name, paramsToString(params))
-		showStack(cr.t)
-		cr.t.FailNow()
+	if expectedCall == nil {
+		cr.unexpected = append(cr.unexpected, len(cr.history))
+		cr.addHistory(Call{Name: name, Params: params})
+		cr.logf("Unexpected call to %s%s", name, cr.formatter.FormatList(params))
+		cr.showLocations("")
+		cr.failNow("Unexpected call to %s", name)
+		return nil, actions{}
+	}
+
+	returns := expectedCall.returns
+	if expectedCall.name != name {
+		// The returns are meant for a different method, and may not suit
+		// this one
+		returns = nil
+	}
+	expectedCall.history = append(expectedCall.history, len(cr.history))
+	if expectedCall.panics && expectedCall.name == name {
+		cr.addHistory(Call{Name: name, Params: params, Panicked: true, Panic: expectedCall.panicValue})
+	} else {
+		cr.addHistory(Call{Name: name, Params: params, Returns: returns})
+	}
+	passed := expectedCall.assert(cr, name, params...)
+	if passed {
+		expectedCall.matched++
+	}
+	expectedCall.made++
+	expectedCall.passed = expectedCall.matched == expectedCall.times
+	if passed && !expectedCall.panics {
+		returns = cr.injectFaults(name, returns)
+		cr.history[len(cr.history)-1].Returns = returns
+	}
+	cr.history[len(cr.history)-1].Passed = passed
+	// Move past the expectations that have been used up. With Unordered()
+	// these need not be the next in line.
+	for cr.current < len(cr.calls) && !cr.calls[cr.current].outstanding() {
+		cr.current++
+	}
+	if expectedCall.name != name {
+		// Nor should the call wait, block or panic as the other method would
+		return returns, actions{}
 	}
+	return returns, cr.actionsFor(name, expectedCall.declared, &expectedCall.response, params)
+}
+
+// nextCall returns the expectation a call should be checked against, or nil
+// if there is none. Normally this is the next expectation in order. With
+// Unordered() it is the first outstanding expectation for the method whose
+// parameters match, or failing that the first outstanding expectation for
+// the method. The lock must be held.
+func (cr *callRecords) nextCall(name string, params []any) *callRecord {
+	if !cr.unordered {
+		if cr.current < len(cr.calls) {
+			return cr.calls[cr.current]
+		}
+		return nil
+	}
+	var first *callRecord
+	for _, call := range cr.calls[cr.current:] {
+		if !call.outstanding() || call.name != name {
+			continue
+		}
+		if cr.paramsMatch(call, params) {
+			return call
+		}
+		if first == nil {
+			first = call
+		}
+	}
+	return first
+}
 
-	expectedCall := cr.calls[cr.current]
-	expectedCall.assert(cr.t, name, params...)
-	cr.current += 1
-	return expectedCall.returns
+// addHistory adds c to the call history, noting the mock it was made to. The
+// lock must be held.
+func (cr *callRecords) addHistory(c Call) {
+	c.Mock, c.MockType = cr.name, cr.mockType
+	cr.history = append(cr.history, c)
+}
+
+func (cr *callRecords) Calls() []Call {
+	cr.Lock()
+	defer cr.Unlock()
+	var calls []Call
+	for _, c := range cr.history {
+		if strings.HasPrefix(c.Name, cr.prefix) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+func (cr *callRecords) Handles(name string) bool {
+	name = cr.qualify(name)
+	cr.Lock()
+	defer cr.Unlock()
+	if _, ok := cr.records[name]; ok || cr.proxy != nil || cr.mode == RecordingMode {
+		return true
+	}
+	if _, ok := cr.defaults[name]; ok && cr.mode == LenientMode {
+		return true
+	}
+	for _, call := range cr.calls[cr.current:] {
+		if call.outstanding() && call.name == name {
+			return true
+		}
+	}
+	return false
 }
 
 func (cr *callRecords) AssertDone() {
-	if cr.current < len(cr.calls) {
+	cr.t.Helper()
+	cr.Lock()
+	defer cr.Unlock()
+	cr.reportFatalLocked()
+	if !cr.done() {
 		// We don't call Fatalf or FailNow because that may mask other errors if this AssertDone
 		// is called from a defer
-		missed := &bytes.Buffer{}
-		for i, call := range cr.calls[cr.current:] {
-			if i != 0 {
+		cr.errorf("%s", cr.missed())
+		for _, call := range cr.calls[cr.current:] {
+			if !call.outstanding() {
+				continue
+			}
+			cr.logf("  %s%s expected at %s", call.name, cr.formatter.FormatList(call.params), call.declared)
+		}
+	}
+}
+
+func (cr *callRecords) Checkpoint() {
+	cr.t.Helper()
+	cr.AssertDone()
+	cr.Lock()
+	defer cr.Unlock()
+	cr.clearExpectations()
+}
+
+func (cr *callRecords) Sub(t testing.TB) CallTracker {
+	child := NewCallRecords(t, WithName(cr.name), WithMockType(cr.mockType)).(*callRecords)
+	child.prefix = cr.prefix
+	cr.Lock()
+	defer cr.Unlock()
+	for name, record := range cr.records {
+		child.records[name] = &recording{
+			response: record.response,
+			params:   make([][]any, 0),
+		}
+	}
+	maps.Copy(child.defaults, cr.defaults)
+	child.mode = cr.mode
+	child.unordered = cr.unordered
+	child.cmpOptions = cr.cmpOptions
+	child.summary = cr.summary
+	child.ignoreContext = cr.ignoreContext
+	child.methods = maps.Clone(cr.methods)
+	child.formatter = cr.formatter
+	child.proxy = cr.proxy
+	t.Cleanup(child.AssertDone)
+	return child
+}
+
+func (cr *callRecords) Reset() CallTracker {
+	cr.Lock()
+	defer cr.Unlock()
+	cr.clearExpectations()
+	cr.records = make(map[string]*recording)
+	cr.defaults = make(map[string][]any)
+	cr.faults = make(map[string][]fault)
+	cr.matchCounts = make(map[string]int)
+	cr.history = nil
+	cr.unexpected = nil
+	return cr
+}
+
+// clearExpectations discards the expected calls. The lock must be held.
+func (cr *callRecords) clearExpectations() {
+	cr.calls = nil
+	cr.current = 0
+	cr.last = nil
+	// Anyone waiting for the calls to be made no longer needs to wait
+	cr.cond.Broadcast()
+}
+
+func (cr *callRecords) AssertDoneWithin(timeout time.Duration) {
+	cr.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := cr.WaitForCalls(ctx); err != nil {
+		cr.logf("Gave up waiting for expected calls after %s", timeout)
+	}
+	cr.AssertDone()
+}
+
+func (cr *callRecords) WaitForCalls(ctx context.Context) error {
+	// Wake the waiter when the context is done. We take the lock so the
+	// wake up can't slip in between the waiter checking the context and
+	// starting to wait.
+	stop := context.AfterFunc(ctx, func() {
+		cr.Lock()
+		defer cr.Unlock()
+		cr.cond.Broadcast()
+	})
+	defer stop()
+
+	cr.Lock()
+	defer cr.Unlock()
+	for !cr.done() {
+		if cr.fatal != "" {
+			return fmt.Errorf("%s", cr.labelled("mock failed: "+cr.fatal))
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%s. %w", cr.labelled(cr.missed()), err)
+		}
+		cr.cond.Wait()
+	}
+	return nil
+}
+
+// done reports whether all the expected calls have been made. The lock must
+// be held.
+func (cr *callRecords) done() bool {
+	return cr.current >= len(cr.calls)
+}
+
+// missed describes the expected calls that have not been made. The lock must
+// be held.
+func (cr *callRecords) missed() string {
+	var expected, made int
+	missed := &bytes.Buffer{}
+	for _, call := range cr.calls {
+		expected += call.times
+		made += call.made
+		for range call.times - call.made {
+			if missed.Len() != 0 {
 				missed.WriteString(", ")
 			}
 			missed.WriteString(call.name)
 		}
-
-		cr.t.Errorf("Only %d of %d expected calls made. Missed calls to %s", cr.current, len(cr.calls), missed)
 	}
+	return fmt.Sprintf("Only %d of %d expected calls made. Missed calls to %s", made, expected, missed)
 }
 
 func (cr *callRecords) GetRecordedParams(name string) ([][]any, bool) {
+	name = cr.qualify(name)
 	cr.Lock()
 	defer cr.Unlock()
 	record, ok := cr.records[name]
@@ -226,6 +828,50 @@ func (cr *callRecords) GetRecordedParams(name string) ([][]any, bool) {
 	return nil, false
 }
 
+// failNow stops the test after a failure. t.FailNow may only be called from
+// the goroutine running the test, but mocks are often called from other
+// goroutines. In that case we mark the test as failed and record the failure
+// so that it is reported again by AssertDone or at the end of the test. The
+// caller must be prepared for failNow to return.
+func (cr *callRecords) failNow(format string, args ...any) {
+	cr.t.Helper()
+	if goid() == cr.goid {
+		cr.t.FailNow()
+	}
+	msg := fmt.Sprintf(format, args...)
+	cr.errorf("%s. The call was made from a goroutine other than the test's so the test cannot be stopped", msg)
+	if cr.fatal == "" {
+		cr.fatal = msg
+	}
+}
+
+func (cr *callRecords) reportFatal() {
+	cr.Lock()
+	defer cr.Unlock()
+	cr.reportFatalLocked()
+}
+
+func (cr *callRecords) reportFatalLocked() {
+	if cr.fatal != "" && !cr.fatalReported {
+		cr.fatalReported = true
+		cr.errorf("Test should have stopped after an earlier failure on another goroutine: %s", cr.fatal)
+	}
+}
+
+// goid returns the ID of the current goroutine. Go deliberately doesn't make
+// this available, so we parse it from the first line of the stack trace,
+// which looks like "goroutine 18 [running]:"
+func goid() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	f := bytes.Fields(buf[:n])
+	if len(f) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseUint(string(f[1]), 10, 64)
+	return id
+}
+
 // NilOrError is a utility function for returning err from mocked methods
 func NilOrError(val any) error {
 	if val == nil {
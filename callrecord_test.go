@@ -1,8 +1,15 @@
 package ut
 
 import (
+	"context"
+	"errors"
 	"io"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/philpearl/ut/uttest"
 )
 
 // For this test we implement a mock of the io.Reader interface
@@ -113,3 +120,111 @@ func TestRecord(t *testing.T) {
 		t.Fatal("grief!")
 	}
 }
+
+func TestFailNowFromGoroutine(t *testing.T) {
+	ft := uttest.NewTB(t)
+	m := &MockReader{NewCallRecords(ft)}
+
+	var r []any
+	ft.Go(func() {
+		r = m.TrackCall("Read", []byte("hat"))
+	})
+
+	if r != nil {
+		t.Errorf("expected no returns, have %v", r)
+	}
+	if !ft.Failed() || len(ft.Errors()) != 1 {
+		t.Fatalf("expected failure to be reported once. %v", ft.Errors())
+	}
+
+	m.AssertDone()
+	if len(ft.Errors()) != 2 || !strings.Contains(ft.Errors()[1], "another goroutine") {
+		t.Fatalf("expected AssertDone to report the earlier failure. %v", ft.Errors())
+	}
+
+	ft.RunCleanups()
+	if len(ft.Errors()) != 2 {
+		t.Fatalf("failure should only be reported again once. %v", ft.Errors())
+	}
+}
+
+func TestFailNowReportedAtCleanup(t *testing.T) {
+	ft := uttest.NewTB(t)
+	m := NewCallRecords(ft)
+
+	ft.Go(func() {
+		m.TrackCall("Read")
+	})
+
+	ft.RunCleanups()
+	if len(ft.Errors()) != 2 || !strings.Contains(ft.Errors()[1], "another goroutine") {
+		t.Fatalf("expected cleanup to report the earlier failure. %v", ft.Errors())
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	m := NewMockReader(t)
+	m.RecordCall("Read", 1, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			m.Read([]byte("hat"))
+		}()
+		go func() {
+			defer wg.Done()
+			m.AddCall("Close").SetReturns(nil)
+			m.GetRecordedParams("Read")
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 10; i++ {
+		m.TrackCall("Close")
+	}
+	m.AssertDone()
+
+	if params, _ := m.GetRecordedParams("Read"); len(params) != 10 {
+		t.Fatalf("expected 10 recorded calls, have %d", len(params))
+	}
+}
+
+func TestAssertDoneWithin(t *testing.T) {
+	m := NewMockReader(t)
+	m.AddCall("Read", []byte("hat")).SetReturns(3, nil)
+	m.AddCall("Read", []byte("cat")).SetReturns(3, nil)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		m.Read([]byte("hat"))
+		m.Read([]byte("cat"))
+	}()
+
+	m.AssertDoneWithin(time.Second)
+}
+
+func TestWaitForCallsTimeout(t *testing.T) {
+	ft := uttest.NewTB(t)
+	m := &MockReader{NewCallRecords(ft)}
+	m.AddCall("Read", []byte("hat")).SetReturns(3, nil)
+	m.AddCall("Read", []byte("cat")).SetReturns(3, nil)
+
+	m.Read([]byte("hat"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := m.WaitForCalls(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, have %v", err)
+	}
+	if exp := "Only 1 of 2 expected calls made. Missed calls to Read. context deadline exceeded"; err.Error() != exp {
+		t.Fatalf("error not as expected. Have %q", err)
+	}
+
+	m.AssertDoneWithin(time.Millisecond)
+	if len(ft.Errors()) != 1 {
+		t.Fatalf("expected AssertDoneWithin to fail. %v", ft.Errors())
+	}
+}
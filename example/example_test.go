@@ -2,6 +2,8 @@ package example
 
 import (
 	"testing"
+
+	"github.com/philpearl/ut"
 )
 
 func TestDoSomething(t *testing.T) {
@@ -29,3 +31,75 @@ func TestDoSomethingElse(t *testing.T) {
 	// Check that all the calls are made
 	mf.AssertDone()
 }
+
+func TestDoSomethingLenient(t *testing.T) {
+	mf := NewMockFred(t)
+	mf.SetMode(ut.LenientMode)
+
+	// We only care that many is called. The call to sanit is unexpected, so
+	// is just logged, and doit returns the default we set.
+	mf.SetDefault("doit", 5)
+	mf.AddCall("many", "a", "b")
+
+	DoSomething(mf)
+
+	mf.AssertDone()
+}
+
+func TestDoSomethingLenientZero(t *testing.T) {
+	mf := NewMockFred(t)
+	mf.SetMode(ut.LenientMode)
+
+	// With no default doit returns 0, so many should not be called.
+	mf.RecordCall("many")
+
+	DoSomething(mf)
+
+	mf.AssertDone()
+	if params, _ := mf.GetRecordedParams("many"); len(params) != 0 {
+		t.Fatalf("many should not be called. Called with %v", params)
+	}
+}
+
+func TestDoSomethingVerify(t *testing.T) {
+	m := NewMockFred(t, ut.Recording())
+	// Unexpected calls return zero values unless a default is set
+	m.SetDefault("doit", 5)
+	DoSomething(m)
+	ut.Verify(m).
+		Called("sanit", "cheese").
+		Called("doit").Times(1).
+		CalledInOrder(ut.Call{Name: "doit", Params: []any{"lemons"}}, ut.Call{Name: "many", Params: []any{"a", "b"}}).
+		NoMoreInteractions()
+}
+
+// fakeFred is a simple working implementation of Fred
+type fakeFred struct {
+	sanitised []string
+}
+
+func (f *fakeFred) sanit(blah string)                   { f.sanitised = append(f.sanitised, blah) }
+func (f *fakeFred) iit(fred any)                        {}
+func (f *fakeFred) many(things ...string)               {}
+func (f *fakeFred) doit(blah string) int                { return len(blah) }
+func (f *fakeFred) donit(blah, fah string) (int, error) { return 0, nil }
+func (f *fakeFred) adonit(blah, fah George, brian func(int) error) (int, error) {
+	return 0, nil
+}
+
+func TestDoSomethingDelegate(t *testing.T) {
+	fake := &fakeFred{}
+	mf := NewMockFred(t)
+	mf.Delegate = fake
+
+	// We only assert on the call to many. The other calls are passed on to
+	// the fake.
+	mf.AddCall("many", "a", "b")
+
+	DoSomething(mf)
+
+	mf.AssertDone()
+	if len(fake.sanitised) != 1 || fake.sanitised[0] != "cheese" {
+		t.Fatalf("sanit not delegated. %v", fake.sanitised)
+	}
+}
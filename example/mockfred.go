@@ -4,17 +4,38 @@ package example
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
+
 	"github.com/philpearl/ut"
 )
 
 type MockFred struct {
 	ut.CallTracker
+	Delegate interface {
+		sanit(blah string)
+		iit(fred any)
+		many(things ...string)
+		doit(blah string) int
+		donit(blah, fah string) (int, error)
+		adonit(blah, fah George, brian func(int) error) (int, error)
+	}
+}
+
+var ut__MockFredMethods = []ut.Method{
+	{Name: "adonit", Params: []reflect.Type{reflect.TypeFor[George](), reflect.TypeFor[George](), reflect.TypeFor[func(int) error]()}, Returns: []reflect.Type{reflect.TypeFor[int](), reflect.TypeFor[error]()}},
+	{Name: "doit", Params: []reflect.Type{reflect.TypeFor[string]()}, Returns: []reflect.Type{reflect.TypeFor[int]()}},
+	{Name: "donit", Params: []reflect.Type{reflect.TypeFor[string](), reflect.TypeFor[string]()}, Returns: []reflect.Type{reflect.TypeFor[int](), reflect.TypeFor[error]()}},
+	{Name: "iit", Params: []reflect.Type{reflect.TypeFor[any]()}},
+	{Name: "many", Params: []reflect.Type{reflect.TypeFor[[]string]()}, Variadic: true},
+	{Name: "sanit", Params: []reflect.Type{reflect.TypeFor[string]()}},
 }
 
-func NewMockFred(t *testing.T) *MockFred {
-	return &MockFred{ut.NewCallRecords(t)}
+func NewMockFred(t testing.TB, opts ...ut.Option) *MockFred {
+	opts = append([]ut.Option{ut.WithMockType("MockFred")}, opts...)
+	return &MockFred{CallTracker: ut.NewCallRecords(t, opts...).SetMethods(ut__MockFredMethods...)}
 }
+
 func (m *MockFred) AddCall(name string, params ...any) ut.CallTracker {
 	switch name {
 	case "adonit", "doit", "donit", "iit", "many", "sanit":
@@ -22,22 +43,37 @@ func (m *MockFred) AddCall(name string, params ...any) ut.CallTracker {
 	default:
 		panic(fmt.Errorf("AddCall: %T has no method %s", m, name))
 	}
-	m.CallTracker.AddCall(name, params...)
-	return m
+	return m.CallTracker.AddCall(name, params...)
 }
+
 func (m *MockFred) SetReturns(params ...any) ut.CallTracker {
 	m.CallTracker.SetReturns(params...)
 	return m
 }
+
 func (i *MockFred) sanit(blah string) {
+	if i.Delegate != nil && !i.Handles("sanit") {
+		i.Delegate.sanit(blah)
+		return
+	}
 	i.TrackCall("sanit", blah)
 	return
 }
+
 func (i *MockFred) iit(fred any) {
+	if i.Delegate != nil && !i.Handles("iit") {
+		i.Delegate.iit(fred)
+		return
+	}
 	i.TrackCall("iit", fred)
 	return
 }
+
 func (i *MockFred) many(things ...string) {
+	if i.Delegate != nil && !i.Handles("many") {
+		i.Delegate.many(things...)
+		return
+	}
 	ut__params := make([]any, 0+len(things))
 	for j, p := range things {
 		ut__params[0+j] = p
@@ -45,35 +81,27 @@ func (i *MockFred) many(things ...string) {
 	i.TrackCall("many", ut__params...)
 	return
 }
+
 func (i *MockFred) doit(blah string) int {
-	r := i.TrackCall("doit", blah)
-	var r_0 int
-	if r[0] != nil {
-		r_0 = r[0].(int)
+	if i.Delegate != nil && !i.Handles("doit") {
+		return i.Delegate.doit(blah)
 	}
-	return r_0
+	r := i.TrackCall("doit", blah)
+	return ut.Ret[int](r, 0)
 }
+
 func (i *MockFred) donit(blah, fah string) (int, error) {
-	r := i.TrackCall("donit", blah, fah)
-	var r_0 int
-	if r[0] != nil {
-		r_0 = r[0].(int)
+	if i.Delegate != nil && !i.Handles("donit") {
+		return i.Delegate.donit(blah, fah)
 	}
-	var r_1 error
-	if r[1] != nil {
-		r_1 = r[1].(error)
-	}
-	return r_0, r_1
+	r := i.TrackCall("donit", blah, fah)
+	return ut.Ret[int](r, 0), ut.Ret[error](r, 1)
 }
+
 func (i *MockFred) adonit(blah, fah George, brian func(int) error) (int, error) {
-	r := i.TrackCall("adonit", blah, fah, brian)
-	var r_0 int
-	if r[0] != nil {
-		r_0 = r[0].(int)
-	}
-	var r_1 error
-	if r[1] != nil {
-		r_1 = r[1].(error)
+	if i.Delegate != nil && !i.Handles("adonit") {
+		return i.Delegate.adonit(blah, fah, brian)
 	}
-	return r_0, r_1
+	r := i.TrackCall("adonit", blah, fah, brian)
+	return ut.Ret[int](r, 0), ut.Ret[error](r, 1)
 }
@@ -0,0 +1,91 @@
+package ut
+
+import "time"
+
+// Expectation is the CallTracker returned by AddCall() and RecordCall().
+// Methods that configure a call, such as SetReturns() and Times(), apply to
+// this expectation, however many expectations have been added since. Other
+// methods are passed on to the tracker, so calls can be chained as before.
+//
+//	read := m.AddCall("Read", p)
+//	m.AddCall("Close")
+//	read.SetReturns(3, nil)
+type Expectation struct {
+	CallTracker
+	cr   *callRecords
+	name string
+	resp *response
+	// call is the expected call, or nil if the expectation was set via
+	// RecordCall
+	call *callRecord
+}
+
+func (e *Expectation) SetReturns(returns ...any) CallTracker {
+	e.cr.configure(e, "SetReturns", func(e *Expectation) { e.setReturns(returns) })
+	return e
+}
+
+func (e *Expectation) WaitFor(ch <-chan struct{}) CallTracker {
+	e.cr.configure(e, "WaitFor", func(e *Expectation) { e.resp.wait = ch })
+	return e
+}
+
+func (e *Expectation) Delay(d time.Duration) CallTracker {
+	e.cr.configure(e, "Delay", func(e *Expectation) { e.resp.delay = d })
+	return e
+}
+
+func (e *Expectation) BlockUntilContextDone() CallTracker {
+	e.cr.configure(e, "BlockUntilContextDone", func(e *Expectation) { e.resp.blockOnContext = true })
+	return e
+}
+
+func (e *Expectation) SetPanic(value any) CallTracker {
+	e.cr.configure(e, "SetPanic", func(e *Expectation) { e.setPanic(value) })
+	return e
+}
+
+func (e *Expectation) Times(n int) CallTracker {
+	e.cr.configure(e, "Times", func(e *Expectation) { e.setTimes(n) })
+	return e
+}
+
+// configure calls fn with the expectation e, or with the expectation most
+// recently added if e is nil. setter names the method doing the configuring,
+// for use in failure messages.
+func (cr *callRecords) configure(e *Expectation, setter string, fn func(e *Expectation)) {
+	cr.Lock()
+	defer cr.Unlock()
+	if e == nil {
+		e = cr.last
+	}
+	if e == nil {
+		cr.setupFailed("%s() called before AddCall() or RecordCall()", setter)
+		return
+	}
+	fn(e)
+}
+
+// The following are called via configure, so with the lock held
+
+func (e *Expectation) setReturns(returns []any) {
+	e.cr.checkReturns(e.name, returns)
+	e.resp.returns = returns
+}
+
+func (e *Expectation) setPanic(value any) {
+	e.resp.panics = true
+	e.resp.panicValue = value
+}
+
+func (e *Expectation) setTimes(n int) {
+	if e.call == nil {
+		e.cr.setupFailed("Times() cannot be used with RecordCall(), which accepts any number of calls to %s", e.name)
+		return
+	}
+	if n < 1 {
+		e.cr.setupFailed("Times() for %s must be at least 1, not %d", e.name, n)
+		return
+	}
+	e.call.times = n
+}
@@ -0,0 +1,131 @@
+package ut
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/philpearl/ut/uttest"
+)
+
+func TestExpectationHandle(t *testing.T) {
+	m := &MockGetter{NewCallRecords(t)}
+	a := m.AddCall("Get", "a")
+	b := m.AddCall("Get", "b")
+	a.SetReturns("apple", nil)
+	b.SetReturns("banana", nil)
+
+	if v, _ := m.Get("a"); v != "apple" {
+		t.Fatalf("expected apple, have %q", v)
+	}
+	if v, _ := m.Get("b"); v != "banana" {
+		t.Fatalf("expected banana, have %q", v)
+	}
+	m.AssertDone()
+}
+
+// legacyMock has the shape of mocks generated before AddCall returned a
+// handle for the expectation. These must still implement CallTracker.
+type legacyMock struct {
+	CallTracker
+}
+
+func (m *legacyMock) AddCall(name string, params ...any) CallTracker {
+	m.CallTracker.AddCall(name, params...)
+	return m
+}
+
+func (m *legacyMock) SetReturns(params ...any) CallTracker {
+	m.CallTracker.SetReturns(params...)
+	return m
+}
+
+var _ CallTracker = &legacyMock{}
+
+func TestChainedAddCallChecked(t *testing.T) {
+	// The second AddCall goes to the tracker rather than the mock, but the
+	// tracker knows the mock's methods
+	tb := uttest.ExpectFailure(t, func(tb testing.TB) {
+		m := &MockReader{NewCallRecords(tb).SetMethods(readerMethods...)}
+		m.AddCall("Read", []byte("hat")).SetReturns(3, nil).AddCall("Reed", []byte("coat"))
+	})
+	if !tb.Contains("The mock has no method Reed") {
+		t.Fatalf("failure not as expected. %q", tb.Logs())
+	}
+}
+
+func TestSetReturnsAfterRecordCall(t *testing.T) {
+	m := &MockGetter{NewCallRecords(t)}
+	m.AddCall("Get", "a").SetReturns("apple", nil)
+	m.RecordCall("Put")
+	m.SetReturns("ignored", nil)
+
+	// The returns apply to the recording, not to the earlier expected call
+	if v, _ := m.Get("a"); v != "apple" {
+		t.Fatalf("expected apple, have %q", v)
+	}
+	m.AssertDone()
+}
+
+func TestSetReturnsBeforeAddCall(t *testing.T) {
+	ft := uttest.ExpectFailure(t, func(tb testing.TB) {
+		m := &MockGetter{NewCallRecords(tb)}
+		m.SetReturns("apple", nil)
+	})
+
+	if !ft.Stopped() {
+		t.Fatal("expected the test to stop")
+	}
+	if len(ft.Logs()) != 1 || !strings.HasSuffix(ft.Logs()[0], "SetReturns() called before AddCall() or RecordCall()") {
+		t.Fatalf("log not as expected. %q", ft.Logs())
+	}
+}
+
+func TestTimes(t *testing.T) {
+	m := &MockGetter{NewCallRecords(t)}
+	m.AddCall("Get", "a").SetReturns("apple", nil).Times(3)
+	m.AddCall("Get", "b").SetReturns("banana", nil)
+
+	for _, key := range []string{"a", "a", "a", "b"} {
+		if v, _ := m.Get(key); v[0] != key[0] {
+			t.Fatalf("unexpected return %q for %q", v, key)
+		}
+	}
+	m.AssertDone()
+}
+
+func TestTimesMissed(t *testing.T) {
+	ft := uttest.NewTB(t)
+	m := &MockGetter{NewCallRecords(ft)}
+	m.AddCall("Get", "a").SetReturns("apple", nil).Times(3)
+	m.AddCall("Get", "b")
+
+	m.Get("a")
+	m.AssertDone()
+
+	exp := "Only 1 of 4 expected calls made. Missed calls to Get, Get, Get"
+	if len(ft.Errors()) != 1 || ft.Errors()[0] != exp {
+		t.Fatalf("errors not as expected. %q", ft.Errors())
+	}
+}
+
+func TestTimesRecordCall(t *testing.T) {
+	ft := uttest.ExpectFailure(t, func(tb testing.TB) {
+		m := &MockGetter{NewCallRecords(tb)}
+		m.RecordCall("Get", "apple", nil).Times(2)
+	})
+	if !ft.Stopped() {
+		t.Fatal("expected the test to stop")
+	}
+}
+
+func TestRecordCallPanic(t *testing.T) {
+	m := &MockGetter{NewCallRecords(t)}
+	m.RecordCall("Get").SetPanic("oh no")
+
+	defer func() {
+		if r := recover(); r != "oh no" {
+			t.Fatalf("expected panic, recovered %v", r)
+		}
+	}()
+	m.Get("a")
+}
@@ -0,0 +1,90 @@
+package ut
+
+import (
+	"math/rand"
+)
+
+// fault injects err into the returns of a matched call if shouldFail returns
+// true. n counts the matched calls to the method, starting at 1.
+type fault struct {
+	shouldFail func(n int) bool
+	err        error
+}
+
+func (cr *callRecords) addFault(name string, f fault) CallTracker {
+	name = cr.qualify(name)
+	cr.Lock()
+	defer cr.Unlock()
+	cr.faults[name] = append(cr.faults[name], f)
+	return cr
+}
+
+func (cr *callRecords) FailNth(name string, n int, err error) CallTracker {
+	return cr.addFault(name, fault{
+		shouldFail: func(i int) bool { return i == n },
+		err:        err,
+	})
+}
+
+func (cr *callRecords) FailEvery(name string, k int, err error) CallTracker {
+	return cr.addFault(name, fault{
+		shouldFail: func(i int) bool { return k > 0 && i%k == 0 },
+		err:        err,
+	})
+}
+
+func (cr *callRecords) FailWithProbability(name string, p float64, err error, seed int64) CallTracker {
+	rnd := rand.New(rand.NewSource(seed))
+	return cr.addFault(name, fault{
+		// This is only called with the tracker lock held, so it is safe to
+		// use rnd
+		shouldFail: func(i int) bool { return rnd.Float64() < p },
+		err:        err,
+	})
+}
+
+// injectFaults counts a matched call to the named method, and returns the
+// returns for the call with any injected error in place. If the method's
+// signature is known the error return is found from that, otherwise by
+// looking at the values. The lock must be held.
+func (cr *callRecords) injectFaults(name string, returns []any) []any {
+	cr.matchCounts[name]++
+	n := cr.matchCounts[name]
+	for _, f := range cr.faults[name] {
+		if !f.shouldFail(n) {
+			continue
+		}
+		i, ok := cr.errorReturn(name)
+		if !ok {
+			i = errorIndex(returns)
+		}
+		if i < 0 {
+			cr.logf("Cannot inject error into call %d to %s as it has no error return value", n, name)
+			cr.t.Fail()
+			return returns
+		}
+		// Copy the returns so we don't change the expectation. They may be
+		// short if no values were given
+		returns = append([]any(nil), returns...)
+		for len(returns) <= i {
+			returns = append(returns, nil)
+		}
+		returns[i] = f.err
+		return returns
+	}
+	return returns
+}
+
+// errorIndex finds the error amongst a method's return values. This is the
+// last value that is either nil or an error.
+func errorIndex(returns []any) int {
+	for i := len(returns) - 1; i >= 0; i-- {
+		if returns[i] == nil {
+			return i
+		}
+		if _, ok := returns[i].(error); ok {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,80 @@
+package ut
+
+import (
+	"errors"
+	"testing"
+)
+
+func getErrors(m *MockGetter, calls int) []error {
+	errs := make([]error, calls)
+	for i := range errs {
+		_, errs[i] = m.Get("a")
+	}
+	return errs
+}
+
+func TestFailNth(t *testing.T) {
+	errFail := errors.New("fail")
+	m := &MockGetter{NewCallRecords(t)}
+	m.RecordCall("Get", "apple", nil)
+	m.FailNth("Get", 2, errFail)
+
+	errs := getErrors(m, 3)
+	if errs[0] != nil || errs[1] != errFail || errs[2] != nil {
+		t.Fatalf("errors not as expected. %v", errs)
+	}
+}
+
+func TestFailEvery(t *testing.T) {
+	errFail := errors.New("fail")
+	m := &MockGetter{NewCallRecords(t)}
+	for i := 0; i < 6; i++ {
+		m.AddCall("Get", "a").SetReturns("apple", nil)
+	}
+	m.FailEvery("Get", 3, errFail)
+
+	errs := getErrors(m, 6)
+	for i, err := range errs {
+		if exp := (i+1)%3 == 0; exp != (err == errFail) {
+			t.Errorf("call %d returned %v", i+1, err)
+		}
+	}
+	m.AssertDone()
+
+	// The expectations themselves should not be changed
+	tr, err := m.Trace(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(tr.Expected[2].Returns[1].Value) != "null" || tr.Calls[2].Returns[1].Type != "error" {
+		t.Fatalf("trace not as expected. %#v", tr)
+	}
+}
+
+func TestFailWithProbability(t *testing.T) {
+	errFail := errors.New("fail")
+	run := func() []error {
+		m := &MockGetter{NewCallRecords(t)}
+		m.RecordCall("Get", "apple", nil)
+		m.FailWithProbability("Get", 0.5, errFail, 37)
+		return getErrors(m, 100)
+	}
+
+	errs := run()
+	var failed int
+	for _, err := range errs {
+		if err == errFail {
+			failed++
+		}
+	}
+	if failed < 25 || failed > 75 {
+		t.Errorf("%d of 100 calls failed", failed)
+	}
+
+	// The same seed should give the same failures
+	for i, err := range run() {
+		if err != errs[i] {
+			t.Fatalf("call %d differs between runs", i)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package ut
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+var updateFixtures = flag.Bool("ut.update", false, "re-record the fixtures used by ut.Fixture")
+
+// Fixture sets up ct to replay the interactions recorded in the fixture file
+// at path as expectations.
+//
+// If the test is run with the -ut.update flag the fixture is re-recorded
+// instead: newReal is called to create a real implementation, ct is put into
+// proxy mode so calls are passed on to it, and the calls made are written to
+// path at the end of the test.
+//
+// Values are converted to and from JSON with DefaultValueEncoder and
+// DefaultValueDecoder, so parameter and return types other than basic types
+// must be registered with RegisterTraceType before the fixture is replayed.
+func Fixture(t testing.TB, ct CallTracker, path string, newReal func() any) {
+	t.Helper()
+	if *updateFixtures {
+		ct.Proxy(newReal())
+		t.Cleanup(func() { writeFixture(t, ct, path) })
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open fixture. Run the test with -ut.update to record it. %v", err)
+	}
+	defer f.Close()
+	tr, err := ReadTrace(f)
+	if err != nil {
+		t.Fatalf("Failed to read fixture %s. %v", path, err)
+	}
+	if err := tr.Replay(ct, nil); err != nil {
+		t.Fatalf("Failed to replay fixture %s. %v", path, err)
+	}
+}
+
+func writeFixture(t testing.TB, ct CallTracker, path string) {
+	if t.Failed() {
+		t.Logf("Not writing fixture %s as the test failed", path)
+		return
+	}
+	tr, err := ct.Trace(nil)
+	if err != nil {
+		t.Errorf("Failed to build fixture %s. %v", path, err)
+		return
+	}
+	// Expectations aren't used in proxy mode, so there is no point keeping them
+	tr.Expected = nil
+	data, err := json.MarshalIndent(tr, "", "  ")
+	if err != nil {
+		t.Errorf("Failed to encode fixture %s. %v", path, err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+		t.Errorf("Failed to create directory for fixture %s. %v", path, err)
+		return
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o666); err != nil {
+		t.Errorf("Failed to write fixture %s. %v", path, err)
+	}
+}
+
+// Replay adds each call in the trace's call history to ct as an expected
+// call, with the recorded return values. Values are decoded with dec, or with
+// DefaultValueDecoder if dec is nil.
+func (tr *Trace) Replay(ct CallTracker, dec ValueDecoder) error {
+	if dec == nil {
+		dec = DefaultValueDecoder
+	}
+	for i, call := range tr.Calls {
+		params, err := decodeValues(dec, call.Params)
+		if err != nil {
+			return fmt.Errorf("call %d to %s, parameters. %w", i, call.Method, err)
+		}
+		returns, err := decodeValues(dec, call.Returns)
+		if err != nil {
+			return fmt.Errorf("call %d to %s, returns. %w", i, call.Method, err)
+		}
+		ct.AddCall(call.Method, params...).SetReturns(returns...)
+	}
+	return nil
+}
+
+func decodeValues(dec ValueDecoder, tvs []TraceValue) ([]any, error) {
+	vals := make([]any, len(tvs))
+	for i, tv := range tvs {
+		v, err := dec(tv)
+		if err != nil {
+			return nil, fmt.Errorf("value %d. %w", i, err)
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+// ValueDecoder converts the serialisable form of a parameter or return value
+// back into a value. It is the inverse of ValueEncoder.
+type ValueDecoder func(tv TraceValue) (any, error)
+
+var traceTypes = struct {
+	sync.RWMutex
+	types map[string]reflect.Type
+}{
+	types: make(map[string]reflect.Type),
+}
+
+func init() {
+	for _, v := range []any{
+		false, "",
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+		[]byte(nil), []string(nil), []int(nil), []any(nil), map[string]any(nil),
+	} {
+		RegisterTraceType(v)
+	}
+}
+
+// RegisterTraceType registers the type of v so that DefaultValueDecoder can
+// decode values of that type. Basic types are registered already.
+func RegisterTraceType(v any) {
+	traceTypes.Lock()
+	defer traceTypes.Unlock()
+	traceTypes.types[fmt.Sprintf("%T", v)] = reflect.TypeOf(v)
+}
+
+// DefaultValueDecoder is the ValueDecoder used when none is specified. It
+// decodes values encoded by DefaultValueEncoder. Errors are decoded as a new
+// error with the same message, and contexts as AnyContext(), so that a
+// replayed call matches whatever context it is given. Other types must be
+// registered via RegisterTraceType.
+func DefaultValueDecoder(tv TraceValue) (any, error) {
+	switch tv.Type {
+	case "":
+		return nil, nil
+	case traceContext:
+		return AnyContext(), nil
+	case "error":
+		var msg string
+		if err := json.Unmarshal(tv.Value, &msg); err != nil {
+			return nil, fmt.Errorf("failed to decode error. %w", err)
+		}
+		return errors.New(msg), nil
+	}
+
+	traceTypes.RLock()
+	typ, ok := traceTypes.types[tv.Type]
+	traceTypes.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("type %s is not registered. Register it with RegisterTraceType", tv.Type)
+	}
+
+	v := reflect.New(typ)
+	if err := json.Unmarshal(tv.Value, v.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to decode %s. %w", tv.Type, err)
+	}
+	return v.Elem().Interface(), nil
+}
+
+func (cr *callRecords) Proxy(real any) CallTracker {
+	cr.Lock()
+	defer cr.Unlock()
+	cr.proxy = real
+	return cr
+}
+
+// proxyCall calls the named method on the proxied real implementation and
+// returns what it returns. It is called with the tracker lock held, but
+// releases it while the real method runs, as that may block or call back
+// into the mock.
+func (cr *callRecords) proxyCall(name string, params []any) []any {
+	cr.t.Helper()
+	m := reflect.ValueOf(cr.proxy).MethodByName(name)
+	if !m.IsValid() {
+		cr.logf("Cannot proxy call to %s%s. %T has no exported method %s", name, cr.formatter.FormatList(params), cr.proxy, name)
+		cr.showLocations("")
+		cr.failNow("Cannot proxy call to %s", name)
+		return nil
+	}
+
+	mt := m.Type()
+	if len(params) < mt.NumIn()-1 || (!mt.IsVariadic() && len(params) != mt.NumIn()) {
+		cr.logf("Cannot proxy call to %s%s. Wrong number of parameters for %s", name, cr.formatter.FormatList(params), mt)
+		cr.showLocations("")
+		cr.failNow("Cannot proxy call to %s", name)
+		return nil
+	}
+	args := make([]reflect.Value, len(params))
+	for i, p := range params {
+		var pt reflect.Type
+		if mt.IsVariadic() && i >= mt.NumIn()-1 {
+			pt = mt.In(mt.NumIn() - 1).Elem()
+		} else {
+			pt = mt.In(i)
+		}
+		if p == nil {
+			args[i] = reflect.Zero(pt)
+			continue
+		}
+		args[i] = reflect.ValueOf(p)
+		if !args[i].Type().AssignableTo(pt) {
+			cr.logf("Cannot proxy call to %s%s. Parameter %d is %T, expected %s", name, cr.formatter.FormatList(params), i, p, pt)
+			cr.showLocations("")
+			cr.failNow("Cannot proxy call to %s", name)
+			return nil
+		}
+	}
+
+	results := func() []reflect.Value {
+		cr.Unlock()
+		defer cr.Lock()
+		return m.Call(args)
+	}()
+	returns := make([]any, len(results))
+	for i, r := range results {
+		if r.Kind() == reflect.Interface && r.IsNil() {
+			continue
+		}
+		returns[i] = r.Interface()
+	}
+	return returns
+}
+
+// copySlices returns params with any slices replaced by copies
+func copySlices(params []any) []any {
+	out := make([]any, len(params))
+	for i, p := range params {
+		v := reflect.ValueOf(p)
+		if v.Kind() != reflect.Slice || v.IsNil() {
+			out[i] = p
+			continue
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(c, v)
+		out[i] = c.Interface()
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+package ut
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockGetter struct {
+	CallTracker
+}
+
+func (m *MockGetter) Get(key string) (string, error) {
+	r := m.TrackCall("Get", key)
+	return r[0].(string), NilOrError(r[1])
+}
+
+type mapGetter map[string]string
+
+func (g mapGetter) Get(key string) (string, error) {
+	v, ok := g[key]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return v, nil
+}
+
+// MockCtxGetter is like MockGetter, but its method takes a context
+type MockCtxGetter struct {
+	CallTracker
+}
+
+func (m *MockCtxGetter) Get(ctx context.Context, key string) (string, error) {
+	r := m.TrackCall("Get", ctx, key)
+	return r[0].(string), NilOrError(r[1])
+}
+
+type mapCtxGetter map[string]string
+
+func (g mapCtxGetter) Get(ctx context.Context, key string) (string, error) {
+	return mapGetter(g).Get(key)
+}
+
+// callbackGetter calls back into the mock that proxies to it
+type callbackGetter struct {
+	m CallTracker
+}
+
+func (g callbackGetter) Get(key string) (string, error) {
+	g.m.GetRecordedParams("Get")
+	return key, nil
+}
+
+func TestProxy(t *testing.T) {
+	m := NewMockReader(t)
+	m.Proxy(strings.NewReader("hello"))
+
+	p := make([]byte, 3)
+	n, err := m.Read(p)
+	if n != 3 || err != nil || string(p) != "hel" {
+		t.Fatalf("unexpected read %d, %v, %q", n, err, p)
+	}
+	m.AssertDone()
+
+	tr, err := m.Trace(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Calls) != 1 || !tr.Calls[0].Passed || string(tr.Calls[0].Returns[0].Value) != "3" {
+		t.Fatalf("call not recorded as expected. %#v", tr.Calls)
+	}
+	// The buffer is recorded as it was passed, not as Read left it
+	if p := m.Calls()[0].Params[0]; !reflect.DeepEqual(p, []byte{0, 0, 0}) {
+		t.Fatalf("parameter not recorded as expected. %#v", p)
+	}
+}
+
+func TestProxyCallback(t *testing.T) {
+	m := &MockGetter{NewCallRecords(t)}
+	m.Proxy(callbackGetter{m: m})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Get("a")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the call back into the mock deadlocked")
+	}
+	if calls := m.Calls(); len(calls) != 1 || calls[0].Returns[0] != "a" {
+		t.Fatalf("call not recorded as expected. %#v", calls)
+	}
+}
+
+func TestFixture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "getter.json")
+
+	t.Run("record", func(t *testing.T) {
+		*updateFixtures = true
+		defer func() { *updateFixtures = false }()
+
+		m := &MockGetter{NewCallRecords(t)}
+		Fixture(t, m, path, func() any { return mapGetter{"a": "apple"} })
+		if v, err := m.Get("a"); v != "apple" || err != nil {
+			t.Fatalf("unexpected returns %q, %v", v, err)
+		}
+		if _, err := m.Get("b"); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		m := &MockGetter{NewCallRecords(t)}
+		Fixture(t, m, path, nil)
+
+		if v, err := m.Get("a"); v != "apple" || err != nil {
+			t.Fatalf("unexpected returns %q, %v", v, err)
+		}
+		if _, err := m.Get("b"); err == nil || err.Error() != "not found" {
+			t.Fatalf("unexpected error %v", err)
+		}
+		m.AssertDone()
+	})
+}
+
+func TestFixtureContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "ctxgetter.json")
+
+	t.Run("record", func(t *testing.T) {
+		*updateFixtures = true
+		defer func() { *updateFixtures = false }()
+
+		m := &MockCtxGetter{NewCallRecords(t)}
+		Fixture(t, m, path, func() any { return mapCtxGetter{"a": "apple"} })
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if v, err := m.Get(ctx, "a"); v != "apple" || err != nil {
+			t.Fatalf("unexpected returns %q, %v", v, err)
+		}
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		m := &MockCtxGetter{NewCallRecords(t)}
+		Fixture(t, m, path, nil)
+
+		if v, err := m.Get(context.Background(), "a"); v != "apple" || err != nil {
+			t.Fatalf("unexpected returns %q, %v", v, err)
+		}
+		m.AssertDone()
+	})
+}
+
+func TestDefaultValueDecoder(t *testing.T) {
+	type thing struct {
+		A int
+		B string
+	}
+	RegisterTraceType(thing{})
+
+	for _, v := range []any{nil, 37, "cheese", []byte("hat"), 3.5, thing{A: 1, B: "b"}} {
+		tv, err := DefaultValueEncoder(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		act, err := DefaultValueDecoder(tv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(act, v) {
+			t.Errorf("%#v decoded as %#v", v, act)
+		}
+	}
+
+	if _, err := DefaultValueDecoder(TraceValue{Type: "unknown.Type", Value: []byte("{}")}); err == nil {
+		t.Errorf("expected an error for an unregistered type")
+	}
+}
@@ -0,0 +1,260 @@
+package ut
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formatter renders parameter and return values in failure messages. Values
+// are shown much as %#v would show them, but pointers are followed rather
+// than shown as addresses, and long slices, arrays and maps are truncated.
+//
+// Set a Formatter on a tracker with SetFormatter.
+type Formatter struct {
+	// MaxElements is the number of elements of a slice, array or map that are
+	// shown. Any more are elided. Zero means there is no limit.
+	MaxElements int
+	// UseStringer shows values that implement fmt.Stringer or error as the
+	// string they return. Values that implement fmt.GoStringer are always
+	// shown using GoString.
+	UseStringer bool
+	// Indent, if not empty, makes struct fields show on separate lines,
+	// indented by Indent for each level of nesting.
+	Indent string
+	// MaxDepth limits how deeply nested values are followed. Zero means there
+	// is no limit, which is unwise if values may contain cycles.
+	MaxDepth int
+
+	formats map[reflect.Type]func(v any) string
+}
+
+// NewFormatter returns the Formatter trackers use by default.
+func NewFormatter() *Formatter {
+	return &Formatter{
+		MaxElements: 20,
+		UseStringer: true,
+		MaxDepth:    10,
+	}
+}
+
+// RegisterFormat makes f show values of type T using format
+func RegisterFormat[T any](f *Formatter, format func(v T) string) {
+	if f.formats == nil {
+		f.formats = make(map[reflect.Type]func(v any) string)
+	}
+	f.formats[reflect.TypeFor[T]()] = func(v any) string { return format(v.(T)) }
+}
+
+// Format returns v as it should be shown in failure messages
+func (f *Formatter) Format(v any) string {
+	var w strings.Builder
+	f.format(&w, reflect.ValueOf(v), 0)
+	return w.String()
+}
+
+// FormatList formats a parameter or return list as it should be shown in
+// failure messages. Matchers are shown as their description.
+func (f *Formatter) FormatList(vals []any) string {
+	var w strings.Builder
+	w.WriteString("(")
+	for i, v := range vals {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.WriteString(f.formatParam(v))
+	}
+	w.WriteString(")")
+	return w.String()
+}
+
+// formatParam formats an expected parameter, which may be a Matcher
+func (f *Formatter) formatParam(v any) string {
+	if m, ok := v.(Matcher); ok {
+		return m.String()
+	}
+	return f.Format(v)
+}
+
+func (f *Formatter) format(w *strings.Builder, v reflect.Value, depth int) {
+	if !v.IsValid() {
+		w.WriteString("<nil>")
+		return
+	}
+	if f.MaxDepth > 0 && depth > f.MaxDepth {
+		w.WriteString("...")
+		return
+	}
+	if v.CanInterface() {
+		if format, ok := f.formats[v.Type()]; ok {
+			w.WriteString(format(v.Interface()))
+			return
+		}
+		// Interfaces are formatted via the value they hold, which may be a
+		// nil pointer that can't be asked for its string
+		if v.Kind() != reflect.Interface {
+			if s, ok := f.asString(v); ok {
+				w.WriteString(s)
+				return
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		w.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.WriteString("0x" + strconv.FormatUint(v.Uint(), 16))
+	case reflect.Float32, reflect.Float64:
+		w.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()))
+	case reflect.Complex64, reflect.Complex128:
+		fmt.Fprintf(w, "%v", v.Complex())
+	case reflect.String:
+		w.WriteString(strconv.Quote(v.String()))
+	case reflect.Pointer:
+		if v.IsNil() {
+			fmt.Fprintf(w, "(%s)(nil)", v.Type())
+			return
+		}
+		w.WriteString("&")
+		f.format(w, v.Elem(), depth)
+	case reflect.Interface:
+		if v.IsNil() {
+			w.WriteString("<nil>")
+			return
+		}
+		f.format(w, v.Elem(), depth)
+	case reflect.Slice:
+		if v.IsNil() {
+			fmt.Fprintf(w, "%s(nil)", typeName(v.Type()))
+			return
+		}
+		f.formatElements(w, v, depth)
+	case reflect.Array:
+		f.formatElements(w, v, depth)
+	case reflect.Map:
+		f.formatMap(w, v, depth)
+	case reflect.Struct:
+		f.formatStruct(w, v, depth)
+	default:
+		// Functions, channels and unsafe pointers
+		if v.IsNil() {
+			fmt.Fprintf(w, "(%s)(nil)", v.Type())
+			return
+		}
+		fmt.Fprintf(w, "(%s)(%#x)", v.Type(), v.Pointer())
+	}
+}
+
+// asString returns v formatted by its own GoString, String or Error method,
+// if it has one that should be used.
+func (f *Formatter) asString(v reflect.Value) (string, bool) {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return "", false
+	}
+	switch s := v.Interface().(type) {
+	case fmt.GoStringer:
+		return s.GoString(), true
+	case error:
+		if f.UseStringer {
+			return s.Error(), true
+		}
+	case fmt.Stringer:
+		if f.UseStringer {
+			return s.String(), true
+		}
+	}
+	return "", false
+}
+
+func (f *Formatter) formatElements(w *strings.Builder, v reflect.Value, depth int) {
+	w.WriteString(typeName(v.Type()))
+	w.WriteString("{")
+	n := f.shown(v.Len())
+	for i := range n {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		f.format(w, v.Index(i), depth+1)
+	}
+	f.elided(w, v.Len()-n)
+	w.WriteString("}")
+}
+
+func (f *Formatter) formatMap(w *strings.Builder, v reflect.Value, depth int) {
+	if v.IsNil() {
+		fmt.Fprintf(w, "%s(nil)", v.Type())
+		return
+	}
+	// Sort the entries so the output is stable
+	entries := make([]string, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		var e strings.Builder
+		f.format(&e, iter.Key(), depth+1)
+		e.WriteString(": ")
+		f.format(&e, iter.Value(), depth+1)
+		entries = append(entries, e.String())
+	}
+	sort.Strings(entries)
+
+	fmt.Fprintf(w, "%s{", v.Type())
+	n := f.shown(len(entries))
+	w.WriteString(strings.Join(entries[:n], ", "))
+	f.elided(w, len(entries)-n)
+	w.WriteString("}")
+}
+
+func (f *Formatter) formatStruct(w *strings.Builder, v reflect.Value, depth int) {
+	fmt.Fprintf(w, "%s{", v.Type())
+	t := v.Type()
+	for i := range t.NumField() {
+		if f.Indent != "" {
+			w.WriteString("\n")
+			w.WriteString(strings.Repeat(f.Indent, depth+1))
+		} else if i > 0 {
+			w.WriteString(", ")
+		}
+		w.WriteString(t.Field(i).Name)
+		if f.Indent != "" {
+			w.WriteString(": ")
+		} else {
+			w.WriteString(":")
+		}
+		f.format(w, v.Field(i), depth+1)
+		if f.Indent != "" {
+			w.WriteString(",")
+		}
+	}
+	if f.Indent != "" && t.NumField() > 0 {
+		w.WriteString("\n")
+		w.WriteString(strings.Repeat(f.Indent, depth))
+	}
+	w.WriteString("}")
+}
+
+// shown returns how many of n elements should be shown
+func (f *Formatter) shown(n int) int {
+	if f.MaxElements > 0 && n > f.MaxElements {
+		return f.MaxElements
+	}
+	return n
+}
+
+// elided notes that n elements have not been shown
+func (f *Formatter) elided(w *strings.Builder, n int) {
+	if n > 0 {
+		fmt.Fprintf(w, ", ... %d more", n)
+	}
+}
+
+// typeName returns the name of the type as %#v would show it
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Slice && t.Elem() == reflect.TypeFor[byte]() {
+		return "[]byte"
+	}
+	return t.String()
+}
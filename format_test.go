@@ -0,0 +1,102 @@
+package ut
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/philpearl/ut/uttest"
+)
+
+type formatInner struct {
+	B []int
+}
+
+type formatOuter struct {
+	A     string
+	Inner *formatInner
+	m     map[string]int
+}
+
+type formatErr struct {
+	msg string
+}
+
+func (e *formatErr) Error() string { return e.msg }
+
+type errHolder struct {
+	Err error
+}
+
+type node struct {
+	Next *node
+}
+
+func TestFormat(t *testing.T) {
+	loop := &node{}
+	loop.Next = loop
+
+	tests := []struct {
+		name string
+		f    *Formatter
+		v    any
+		exp  string
+	}{
+		{name: "nil", f: NewFormatter(), v: nil, exp: "<nil>"},
+		{name: "typed nil in interface", f: NewFormatter(), v: errHolder{Err: (*formatErr)(nil)}, exp: "ut.errHolder{Err:(*ut.formatErr)(nil)}"},
+		{name: "error in interface", f: NewFormatter(), v: errHolder{Err: &formatErr{msg: "oops"}}, exp: "ut.errHolder{Err:oops}"},
+		{name: "int", f: NewFormatter(), v: 37, exp: "37"},
+		{name: "string", f: NewFormatter(), v: "hat", exp: `"hat"`},
+		{name: "bytes", f: NewFormatter(), v: []byte("hat"), exp: "[]byte{0x68, 0x61, 0x74}"},
+		{name: "nil slice", f: NewFormatter(), v: []int(nil), exp: "[]int(nil)"},
+		{name: "truncated", f: &Formatter{MaxElements: 3}, v: bytes.Repeat([]byte("a"), 100), exp: "[]byte{0x61, 0x61, 0x61, ... 97 more}"},
+		{name: "map", f: &Formatter{MaxElements: 2}, v: map[string]int{"c": 3, "b": 2, "a": 1}, exp: `map[string]int{"a": 1, "b": 2, ... 1 more}`},
+		{
+			name: "struct",
+			f:    NewFormatter(),
+			v:    formatOuter{A: "a", Inner: &formatInner{B: []int{1}}, m: map[string]int{"x": 1}},
+			exp:  `ut.formatOuter{A:"a", Inner:&ut.formatInner{B:[]int{1}}, m:map[string]int{"x": 1}}`,
+		},
+		{name: "nil pointer", f: NewFormatter(), v: (*formatInner)(nil), exp: "(*ut.formatInner)(nil)"},
+		{
+			name: "multi-line",
+			f:    &Formatter{Indent: "  "},
+			v:    formatOuter{A: "a", Inner: &formatInner{}},
+			exp:  "ut.formatOuter{\n  A: \"a\",\n  Inner: &ut.formatInner{\n    B: []int(nil),\n  },\n  m: map[string]int(nil),\n}",
+		},
+		{name: "stringer", f: NewFormatter(), v: time.Second, exp: "1s"},
+		{name: "no stringer", f: &Formatter{}, v: time.Second, exp: "1000000000"},
+		{name: "error", f: NewFormatter(), v: errors.New("oops"), exp: "oops"},
+		{name: "cycle", f: &Formatter{MaxDepth: 2}, v: loop, exp: "&ut.node{Next:&ut.node{Next:&ut.node{Next:...}}}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if act := test.f.Format(test.v); act != test.exp {
+				t.Fatalf("expected %q, have %q", test.exp, act)
+			}
+		})
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	f := NewFormatter()
+	RegisterFormat(f, func(v *formatInner) string { return "inner" })
+
+	if act := f.FormatList([]any{formatOuter{Inner: &formatInner{}}, AnyContext()}); act != `(ut.formatOuter{A:"", Inner:inner, m:map[string]int(nil)}, AnyContext())` {
+		t.Fatalf("unexpected format %q", act)
+	}
+}
+
+func TestSetFormatter(t *testing.T) {
+	ft := uttest.ExpectFailure(t, func(tb testing.TB) {
+		m := &MockReader{NewCallRecords(tb).SetFormatter(&Formatter{MaxElements: 2})}
+		m.Read([]byte("hat"))
+	})
+
+	exp := "Unexpected call to Read([]byte{0x68, 0x61, ... 1 more})"
+	if len(ft.Logs()) == 0 || ft.Logs()[0] != exp {
+		t.Fatalf("expected %q, have %q", exp, ft.Logs())
+	}
+}
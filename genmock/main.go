@@ -13,6 +13,7 @@ import (
 	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	gofumpt "mvdan.cc/gofumpt/format"
@@ -138,10 +139,21 @@ func buildMockForInterface(o *options, t *ast.InterfaceType, imports []*ast.Impo
 
 	t.Methods.List = dedupeFields(t.Methods.List)
 
+	// The mock passes its parameters on, so they all need names
+	for _, m := range t.Methods.List {
+		if ft, ok := m.Type.(*ast.FuncType); ok {
+			nameParams(ft.Params)
+		}
+	}
+
 	// Mock Implementation of the interface
 	methodNames := fieldListNames(t.Methods.List)
 	sort.Strings(methodNames)
-	mockAst, fset, err := buildBasicFile(o.targetPackage, o.mockName, methodNames)
+	// The mock's Delegate field is an interface with the same methods as
+	// the one we're mocking. We spell the methods out rather than naming the
+	// interface so we don't need to worry about where it is defined.
+	delegateType := &ast.InterfaceType{Methods: &ast.FieldList{List: t.Methods.List}}
+	mockAst, fset, err := buildBasicFile(o.targetPackage, o.mockName, methodNames, delegateType, o.ignoreContext)
 	if err != nil {
 		fmt.Printf("Failed to parse basic AST. %v", err)
 		os.Exit(2)
@@ -166,6 +178,10 @@ func buildMockForInterface(o *options, t *ast.InterfaceType, imports []*ast.Impo
 		}
 	}
 
+	// The method signatures go straight after the mock type declaration
+	methodsDecl := genMethodsDecl(fset, o.mockName, t.Methods.List)
+	mockAst.Decls = append(mockAst.Decls[:1], append([]ast.Decl{methodsDecl}, mockAst.Decls[1:]...)...)
+
 	addImportsToMock(mockAst, fset, imports)
 	var buf bytes.Buffer
 	err = format.Node(&buf, fset, mockAst)
@@ -253,6 +269,12 @@ func addImportsToMock(mockAst *ast.File, fset *token.FileSet, imports []*ast.Imp
 		&ast.ImportSpec{Path: &ast.BasicLit{Value: `"testing"`, Kind: token.STRING}},
 		&ast.ImportSpec{Path: &ast.BasicLit{Value: `"github.com/philpearl/ut"`, Kind: token.STRING}},
 	}
+	// The method signatures use reflect, unless no method has any parameters
+	// or return values
+	if _, ok := fi.names["reflect"]; ok {
+		found[Imp{path: `"reflect"`}] = struct{}{}
+		usedImports = append(usedImports, &ast.ImportSpec{Path: &ast.BasicLit{Value: `"reflect"`, Kind: token.STRING}})
+	}
 	for _, is := range imports {
 		if fi.isUsed(is) {
 			var imp Imp
@@ -296,12 +318,23 @@ func removeFieldNames(fl *ast.FieldList) {
 	fl.List = l
 }
 
-func buildBasicFile(packageName, mockName string, methodNames []string) (*ast.File, *token.FileSet, error) {
+// nameParams gives unnamed parameters the names p0, p1 and so on, in place.
+// Go doesn't allow a mix of named and unnamed parameters, so either all the
+// parameters are named already or none are.
+func nameParams(fl *ast.FieldList) {
+	for i, f := range fl.List {
+		if len(f.Names) == 0 {
+			f.Names = []*ast.Ident{ast.NewIdent(fmt.Sprintf("p%d", i))}
+		}
+	}
+}
+
+func buildBasicFile(packageName, mockName string, methodNames []string, delegateType ast.Expr, ignoreContext bool) (*ast.File, *token.FileSet, error) {
 	fset := token.NewFileSet()
 	file := &ast.File{
 		Name: ast.NewIdent(packageName),
 	}
-	file.Decls = genBasicDecls(mockName, methodNames)
+	file.Decls = genBasicDecls(mockName, methodNames, delegateType, ignoreContext)
 	return file, fset, nil
 }
 
@@ -327,18 +360,11 @@ func buildMethodReceiver(name string) *ast.FieldList {
 
 The function body needs to look something like:
 
+	if i.Delegate != nil && !i.Handles("method") {
+		return i.Delegate.method(param1, param2)
+	}
 	r := ut.TrackCall("method", param1, param2)
-	return r[0].(int), r[1].(thing)
-
-... except we need to worry about types a little more for the
-return values.  So instead we do
-
-	r := ut.TrackCall("method", param1, param2)
-	var r_0 int
-	var r_1 thing
-	if r[0] != nil { r_0 = r[0].(int) }
-	if r[1] != nil { r_1 = r[1].(thing) }
-	return r_0, r_1
+	return ut.Ret[int](r, 0), ut.Ret[thing](r, 1)
 
 ... and we might have an ellipsis parameter so in fact we do
 
@@ -346,14 +372,10 @@ return values.  So instead we do
 	ut__params[0] = param1
 	ut__params[1] = param2
 	r := ut.TrackCall("method", ut__params...)
-	var r_0 int
-	var r_1 thing
-	if r[0] != nil { r_0 = r[0].(int) }
-	if r[1] != nil { r_1 = r[1].(thing) }
-	return r_0, r_1
+	return ut.Ret[int](r, 0), ut.Ret[thing](r, 1)
 */
 func buildMockMethod(recv *ast.FieldList, name string, t *ast.FuncType) *ast.FuncDecl {
-	stmts := []ast.Stmt{}
+	stmts := []ast.Stmt{delegateCall(t.Results.NumFields(), name, t.Params)}
 	p, ellipsis, err := storeParams(t.Params)
 	if err != nil {
 		fmt.Printf("Failed to set up call parameters. %v", err)
@@ -368,13 +390,7 @@ func buildMockMethod(recv *ast.FieldList, name string, t *ast.FuncType) *ast.Fun
 	}
 	stmts = append(stmts, p...)
 
-	p, err = declReturnValues(t.Results)
-	if err != nil {
-		fmt.Printf("failed to declare return values. %v", err)
-	}
-	stmts = append(stmts, p...)
-
-	p, err = buildReturnStatement(t.Results.NumFields())
+	p, err = buildReturnStatement(t.Results)
 	if err != nil {
 		fmt.Printf("failed to build return statement. %v", err)
 	}
@@ -493,6 +509,72 @@ func storeParams(params *ast.FieldList) ([]ast.Stmt, bool, error) {
 	return nil, false, nil
 }
 
+// delegateCall builds the ast that passes the call on to the mock's Delegate
+// if the CallTracker won't handle it.
+//
+//	if i.Delegate != nil && !i.Handles("method") {
+//		return i.Delegate.method(params...)
+//	}
+//
+// If there are no return values the call and the return are separate
+// statements
+func delegateCall(numReturns int, methodName string, params *ast.FieldList) ast.Stmt {
+	args := []ast.Expr{}
+	for _, f := range params.List {
+		for _, n := range f.Names {
+			if _, ok := f.Type.(*ast.Ellipsis); ok {
+				args = append(args, ast.NewIdent(n.Name+"..."))
+			} else {
+				args = append(args, ast.NewIdent(n.Name))
+			}
+		}
+	}
+	callExpr := &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X: &ast.SelectorExpr{
+				X:   ast.NewIdent("i"),
+				Sel: ast.NewIdent("Delegate"),
+			},
+			Sel: ast.NewIdent(methodName),
+		},
+		Args: args,
+	}
+
+	var body []ast.Stmt
+	if numReturns != 0 {
+		body = []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{callExpr}}}
+	} else {
+		body = []ast.Stmt{&ast.ExprStmt{X: callExpr}, &ast.ReturnStmt{}}
+	}
+
+	return &ast.IfStmt{
+		Cond: &ast.BinaryExpr{
+			X: &ast.BinaryExpr{
+				X: &ast.SelectorExpr{
+					X:   ast.NewIdent("i"),
+					Sel: ast.NewIdent("Delegate"),
+				},
+				Op: token.NEQ,
+				Y:  ast.NewIdent("nil"),
+			},
+			Op: token.LAND,
+			Y: &ast.UnaryExpr{
+				Op: token.NOT,
+				X: &ast.CallExpr{
+					Fun: &ast.SelectorExpr{
+						X:   ast.NewIdent("i"),
+						Sel: ast.NewIdent("Handles"),
+					},
+					Args: []ast.Expr{
+						&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(methodName)},
+					},
+				},
+			},
+		},
+		Body: &ast.BlockStmt{List: body},
+	}
+}
+
 // trackCall builds the ast for the call expression.
 //
 // The call looks like
@@ -538,78 +620,34 @@ func trackCall(numReturns int, methodName string, ellipsis bool, params *ast.Fie
 	return stmts, nil
 }
 
-// declReturnValues builds the return part of the call
-func declReturnValues(results *ast.FieldList) ([]ast.Stmt, error) {
-	if results.NumFields() == 0 {
-		return nil, nil
+// buildReturnStatement builds the return part of the call. ut.Ret converts
+// each return value to the right type, coping with nil values and with r
+// being short if the tracker returns defaults for an unexpected call.
+//
+//	return ut.Ret[int](r, 0), ut.Ret[thing](r, 1)
+func buildReturnStatement(results *ast.FieldList) ([]ast.Stmt, error) {
+	r := &ast.ReturnStmt{}
+	if results == nil {
+		return []ast.Stmt{r}, nil
 	}
-	stmts := []ast.Stmt{}
 	for i, f := range results.List {
-		// var r_X type
-		stmts = append(stmts, &ast.DeclStmt{
-			Decl: &ast.GenDecl{
-				Tok: token.VAR,
-				Specs: []ast.Spec{
-					&ast.ValueSpec{
-						Names: []*ast.Ident{
-							ast.NewIdent(fmt.Sprintf("r_%d", i)),
-						},
-						Type: f.Type,
-					},
+		r.Results = append(r.Results, &ast.CallExpr{
+			Fun: &ast.IndexExpr{
+				X: &ast.SelectorExpr{
+					X:   ast.NewIdent("ut"),
+					Sel: ast.NewIdent("Ret"),
 				},
+				Index: f.Type,
 			},
-		})
-		// if r[X] != nil {
-		//     r_X = r[X].(type)
-		// }
-		stmts = append(stmts, &ast.IfStmt{
-			Cond: &ast.BinaryExpr{
-				X: &ast.IndexExpr{
-					X: ast.NewIdent("r"),
-					Index: &ast.BasicLit{
-						Kind:  token.INT,
-						Value: fmt.Sprintf("%d", i),
-					},
-				},
-				Op: token.NEQ,
-				Y:  ast.NewIdent("nil"),
-			},
-			Body: &ast.BlockStmt{
-				List: []ast.Stmt{
-					&ast.AssignStmt{
-						Lhs: []ast.Expr{
-							ast.NewIdent(fmt.Sprintf("r_%d", i)),
-						},
-						Tok: token.ASSIGN,
-						Rhs: []ast.Expr{
-							&ast.TypeAssertExpr{
-								X: &ast.IndexExpr{
-									X: ast.NewIdent("r"),
-									Index: &ast.BasicLit{
-										Kind:  token.INT,
-										Value: fmt.Sprintf("%d", i),
-									},
-								},
-								Type: f.Type,
-							},
-						},
-					},
+			Args: []ast.Expr{
+				ast.NewIdent("r"),
+				&ast.BasicLit{
+					Kind:  token.INT,
+					Value: fmt.Sprintf("%d", i),
 				},
 			},
 		})
 	}
-
-	return stmts, nil
-}
-
-// buildReturnStatement
-//
-// return r_0, r_1, r_2
-func buildReturnStatement(count int) ([]ast.Stmt, error) {
-	r := &ast.ReturnStmt{}
-	for i := 0; i < count; i++ {
-		r.Results = append(r.Results, ast.NewIdent(fmt.Sprintf("r_%d", i)))
-	}
 	return []ast.Stmt{r}, nil
 }
 
@@ -686,6 +724,9 @@ type options struct {
 	mockName string
 	// Name of the package the mock should be created in
 	targetPackage string
+	// Whether the mock lets tests leave context.Context parameters out of
+	// expected calls
+	ignoreContext bool
 
 	pkg *build.Package
 }
@@ -729,6 +770,7 @@ func (o *options) setup() {
 	flag.StringVar(&o.outfile, "outfile", "", "The file to create the mock in. By default will use mock<interface>.go in the current directory.")
 	flag.StringVar(&o.mockName, "mock", "", "The name for the mock class. By default will use Mock<interface>.")
 	flag.StringVar(&o.targetPackage, "mock-package", "", "Package name to use for the mock file; Must be specified.")
+	flag.BoolVar(&o.ignoreContext, "ignore-context", false, "Allow tests to leave context.Context parameters out of expected calls.")
 }
 
 func main() {
@@ -31,6 +31,29 @@ func TestNestedInterfaces(t *testing.T) {
 	})
 }
 
+func TestIgnoreContext(t *testing.T) {
+	generateMock(&options{
+		packagePath:   "testcode",
+		ifName:        "ContextInterface",
+		outfile:       filepath.Join("gentestfile", "ignorecontext.go"),
+		targetPackage: "testcode",
+		mockName:      "MockContextInterface",
+		ignoreContext: true,
+	})
+	assertFileContent(t, "gentestfile/ignorecontext.go", "gentestfile/ignorecontext.golden")
+}
+
+func TestUnnamedParams(t *testing.T) {
+	generateMock(&options{
+		packagePath:   "testcode",
+		ifName:        "UnnamedInterface",
+		outfile:       filepath.Join("gentestfile", "unnamed.go"),
+		targetPackage: "testcode",
+		mockName:      "MockUnnamedInterface",
+	})
+	assertFileContent(t, "gentestfile/unnamed.go", "gentestfile/unnamed.golden")
+}
+
 func assertFileContent(t *testing.T, actFile, expFile string) {
 	act, err := os.ReadFile(actFile)
 	if err != nil {
@@ -3,6 +3,7 @@ package main
 import (
 	"go/ast"
 	"go/token"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -10,20 +11,25 @@ import (
 // This generates some basic scaffold that looks like:
 // type mockName struct {
 //   ut.CallTracker
+//   Delegate interface { ... }
 // }
 
-// func NewmockName(t *testing.T) *mockName {
-//   return &mockName{ut.NewCallRecords(t)}
+// func NewmockName(t testing.TB, opts ...ut.Option) *mockName {
+//   opts = append([]ut.Option{ut.WithMockType("mockName")}, opts...)
+//   return &mockName{CallTracker: ut.NewCallRecords(t, opts...).SetMethods(ut__mockNameMethods...)}
 // }
+//
+// If ignoreContext is set the tracker is also configured with
+// SetIgnoreContext(true)
+//
 // func (m *mockName) AddCall(name string, params ...any) ut.CallTracker {
-//   m.CallTracker.AddCall(name, params)
-//   return m
+//   return m.CallTracker.AddCall(name, params)
 // }
 // func (m *mockName) SetReturns(params ...any) ut.CallTracker {
 //   m.CallTracker.SetReturns(params)
 //   return m
 // }
-func genBasicDecls(mockName string, methodNames []string) []ast.Decl {
+func genBasicDecls(mockName string, methodNames []string, delegateType ast.Expr, ignoreContext bool) []ast.Decl {
 	return []ast.Decl{
 		&ast.GenDecl{
 			Tok: token.TYPE,
@@ -32,7 +38,10 @@ func genBasicDecls(mockName string, methodNames []string) []ast.Decl {
 					Name: ast.NewIdent(mockName),
 					Type: &ast.StructType{
 						Fields: &ast.FieldList{
-							List: []*ast.Field{{Type: &ast.SelectorExpr{X: ast.NewIdent("ut"), Sel: ast.NewIdent("CallTracker")}}},
+							List: []*ast.Field{
+								{Type: &ast.SelectorExpr{X: ast.NewIdent("ut"), Sel: ast.NewIdent("CallTracker")}},
+								{Names: []*ast.Ident{ast.NewIdent("Delegate")}, Type: delegateType},
+							},
 						},
 					},
 				},
@@ -45,10 +54,17 @@ func genBasicDecls(mockName string, methodNames []string) []ast.Decl {
 					List: []*ast.Field{
 						{
 							Names: []*ast.Ident{ast.NewIdent("t")},
-							Type: &ast.StarExpr{
-								X: &ast.SelectorExpr{
-									X:   ast.NewIdent("testing"),
-									Sel: ast.NewIdent("T"),
+							Type: &ast.SelectorExpr{
+								X:   ast.NewIdent("testing"),
+								Sel: ast.NewIdent("TB"),
+							},
+						},
+						{
+							Names: []*ast.Ident{ast.NewIdent("opts")},
+							Type: &ast.Ellipsis{
+								Elt: &ast.SelectorExpr{
+									X:   ast.NewIdent("ut"),
+									Sel: ast.NewIdent("Option"),
 								},
 							},
 						},
@@ -66,6 +82,32 @@ func genBasicDecls(mockName string, methodNames []string) []ast.Decl {
 			},
 			Body: &ast.BlockStmt{
 				List: []ast.Stmt{
+					// The mock is named after its type unless the options
+					// say otherwise
+					&ast.AssignStmt{
+						Lhs: []ast.Expr{ast.NewIdent("opts")},
+						Tok: token.ASSIGN,
+						Rhs: []ast.Expr{
+							&ast.CallExpr{
+								Fun: ast.NewIdent("append"),
+								Args: []ast.Expr{
+									&ast.CompositeLit{
+										Type: &ast.ArrayType{
+											Elt: &ast.SelectorExpr{X: ast.NewIdent("ut"), Sel: ast.NewIdent("Option")},
+										},
+										Elts: []ast.Expr{
+											&ast.CallExpr{
+												Fun:  &ast.SelectorExpr{X: ast.NewIdent("ut"), Sel: ast.NewIdent("WithMockType")},
+												Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(mockName)}},
+											},
+										},
+									},
+									ast.NewIdent("opts"),
+								},
+								Ellipsis: 1,
+							},
+						},
+					},
 					&ast.ReturnStmt{
 						Results: []ast.Expr{
 							&ast.UnaryExpr{
@@ -73,14 +115,9 @@ func genBasicDecls(mockName string, methodNames []string) []ast.Decl {
 								X: &ast.CompositeLit{
 									Type: ast.NewIdent(mockName),
 									Elts: []ast.Expr{
-										&ast.CallExpr{
-											Fun: &ast.SelectorExpr{
-												X:   ast.NewIdent("ut"),
-												Sel: ast.NewIdent("NewCallRecords"),
-											},
-											Args: []ast.Expr{
-												ast.NewIdent("t"),
-											},
+										&ast.KeyValueExpr{
+											Key:   ast.NewIdent("CallTracker"),
+											Value: newCallRecords(mockName, ignoreContext),
 										},
 									},
 								},
@@ -179,24 +216,21 @@ func genBasicDecls(mockName string, methodNames []string) []ast.Decl {
 							},
 						},
 					},
-					&ast.ExprStmt{
-						X: &ast.CallExpr{
-							Fun: &ast.SelectorExpr{
-								X: &ast.SelectorExpr{
-									X:   ast.NewIdent("m"),
-									Sel: ast.NewIdent("CallTracker"),
-								},
-								Sel: ast.NewIdent("AddCall"),
-							},
-							Args: []ast.Expr{
-								ast.NewIdent("name"),
-								ast.NewIdent("params..."),
-							},
-						},
-					},
 					&ast.ReturnStmt{
 						Results: []ast.Expr{
-							ast.NewIdent("m"),
+							&ast.CallExpr{
+								Fun: &ast.SelectorExpr{
+									X: &ast.SelectorExpr{
+										X:   ast.NewIdent("m"),
+										Sel: ast.NewIdent("CallTracker"),
+									},
+									Sel: ast.NewIdent("AddCall"),
+								},
+								Args: []ast.Expr{
+									ast.NewIdent("name"),
+									ast.NewIdent("params..."),
+								},
+							},
 						},
 					},
 				},
@@ -268,6 +302,193 @@ func genBasicDecls(mockName string, methodNames []string) []ast.Decl {
 	}
 }
 
+// newCallRecords builds the expression that creates the mock's CallTracker
+func newCallRecords(mockName string, ignoreContext bool) ast.Expr {
+	var expr ast.Expr = &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X: &ast.CallExpr{
+				Fun: &ast.SelectorExpr{
+					X:   ast.NewIdent("ut"),
+					Sel: ast.NewIdent("NewCallRecords"),
+				},
+				Args: []ast.Expr{
+					ast.NewIdent("t"),
+					ast.NewIdent("opts..."),
+				},
+			},
+			Sel: ast.NewIdent("SetMethods"),
+		},
+		Args: []ast.Expr{
+			ast.NewIdent(methodsVarName(mockName) + "..."),
+		},
+	}
+	if ignoreContext {
+		expr = &ast.CallExpr{
+			Fun: &ast.SelectorExpr{
+				X:   expr,
+				Sel: ast.NewIdent("SetIgnoreContext"),
+			},
+			Args: []ast.Expr{
+				ast.NewIdent("true"),
+			},
+		}
+	}
+	return expr
+}
+
+// genMethodsDecl generates the signatures of the mock's methods, which the
+// CallTracker uses to check expectations as they are set up.
+//
+// var ut__mockNameMethods = []ut.Method{
+//   {Name: "method", Params: []reflect.Type{reflect.TypeFor[string]()}, Returns: []reflect.Type{reflect.TypeFor[int](), reflect.TypeFor[error]()}},
+//   {Name: "variadic", Params: []reflect.Type{reflect.TypeFor[[]string]()}, Variadic: true},
+// }
+func genMethodsDecl(fset *token.FileSet, mockName string, fields []*ast.Field) ast.Decl {
+	type method struct {
+		name string
+		t    *ast.FuncType
+	}
+	var methods []method
+	for _, f := range fields {
+		if t, ok := f.Type.(*ast.FuncType); ok {
+			for _, n := range f.Names {
+				methods = append(methods, method{name: n.Name, t: t})
+			}
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].name < methods[j].name })
+
+	elts := make([]ast.Expr, len(methods))
+	for i, m := range methods {
+		lit := &ast.CompositeLit{
+			Elts: []ast.Expr{
+				&ast.KeyValueExpr{
+					Key:   ast.NewIdent("Name"),
+					Value: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(m.name)},
+				},
+			},
+		}
+		if m.t.Params.NumFields() > 0 {
+			lit.Elts = append(lit.Elts, &ast.KeyValueExpr{
+				Key:   ast.NewIdent("Params"),
+				Value: typeList(m.t.Params),
+			})
+			last := m.t.Params.List[len(m.t.Params.List)-1]
+			if _, ok := last.Type.(*ast.Ellipsis); ok {
+				lit.Elts = append(lit.Elts, &ast.KeyValueExpr{
+					Key:   ast.NewIdent("Variadic"),
+					Value: ast.NewIdent("true"),
+				})
+			}
+		}
+		if m.t.Results.NumFields() > 0 {
+			lit.Elts = append(lit.Elts, &ast.KeyValueExpr{
+				Key:   ast.NewIdent("Returns"),
+				Value: typeList(m.t.Results),
+			})
+		}
+		elts[i] = lit
+	}
+	outer := &ast.CompositeLit{
+		Type: &ast.ArrayType{
+			Elt: &ast.SelectorExpr{X: ast.NewIdent("ut"), Sel: ast.NewIdent("Method")},
+		},
+		Elts: elts,
+	}
+	onSeparateLines(fset, outer)
+
+	return &ast.GenDecl{
+		Tok: token.VAR,
+		Specs: []ast.Spec{
+			&ast.ValueSpec{
+				Names: []*ast.Ident{ast.NewIdent(methodsVarName(mockName))},
+				Values: []ast.Expr{outer},
+			},
+		},
+	}
+}
+
+// onSeparateLines arranges for the printer to put each element of the
+// composite literal on its own line. The printer decides on line breaks from
+// the positions of the nodes, so we add a file to fset with a line for the
+// opening brace, one for each element, and one for the closing brace, and
+// position every node of each element on its line.
+func onSeparateLines(fset *token.FileSet, lit *ast.CompositeLit) {
+	lines := len(lit.Elts) + 2
+	f := fset.AddFile("", -1, lines)
+	offsets := make([]int, lines)
+	for i := range offsets {
+		offsets[i] = i
+	}
+	f.SetLines(offsets)
+
+	lit.Lbrace = f.LineStart(1)
+	for i, elt := range lit.Elts {
+		pos := f.LineStart(i + 2)
+		var place func(n ast.Node) bool
+		place = func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.Ident:
+				n.NamePos = pos
+			case *ast.BasicLit:
+				n.ValuePos = pos
+			case *ast.CompositeLit:
+				n.Lbrace, n.Rbrace = pos, pos
+			case *ast.KeyValueExpr:
+				n.Colon = pos
+			case *ast.CallExpr:
+				n.Lparen, n.Rparen = pos, pos
+			case *ast.IndexExpr:
+				// The index is a type taken from the interface definition,
+				// so we must leave its positions alone
+				n.Lbrack, n.Rbrack = pos, pos
+				ast.Inspect(n.X, place)
+				return false
+			}
+			return true
+		}
+		ast.Inspect(elt, place)
+	}
+	lit.Rbrace = f.LineStart(lines)
+}
+
+// typeList builds a []reflect.Type literal for the types in the field list.
+// Fields may have more than one name, in which case the type is repeated.
+func typeList(fl *ast.FieldList) ast.Expr {
+	lit := &ast.CompositeLit{
+		Type: &ast.ArrayType{
+			Elt: &ast.SelectorExpr{X: ast.NewIdent("reflect"), Sel: ast.NewIdent("Type")},
+		},
+	}
+	for _, f := range fl.List {
+		typ := f.Type
+		if e, ok := typ.(*ast.Ellipsis); ok {
+			// reflect describes a variadic parameter as a slice
+			typ = &ast.ArrayType{Elt: e.Elt}
+		}
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			lit.Elts = append(lit.Elts, &ast.CallExpr{
+				Fun: &ast.IndexExpr{
+					X:     &ast.SelectorExpr{X: ast.NewIdent("reflect"), Sel: ast.NewIdent("TypeFor")},
+					Index: typ,
+				},
+			})
+		}
+	}
+	return lit
+}
+
+// methodsVarName is the name of the variable holding the mock's method
+// signatures. The prefix keeps it unexported, and distinct for mocks whose
+// names differ only in the case of the first letter.
+func methodsVarName(mockName string) string {
+	return "ut__" + mockName + "Methods"
+}
+
 func constructorName(typeName string) string {
 	if len(typeName) > 0 && typeName[0] >= 'a' && typeName[0] <= 'z' {
 		return "new" + strings.Title(typeName)
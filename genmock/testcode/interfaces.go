@@ -1,5 +1,7 @@
 package testcode
 
+import "context"
+
 /*
 	These interface definitions are here for the `TestNestedInterfaces`
 	To check the generating nested interfaces works
@@ -42,3 +44,14 @@ func (i *Interface4Impl) Method3(value3 string) error {
 func (i *Interface4Impl) Method4(value4 string) error {
 	return nil
 }
+
+// UnnamedInterface is used by `TestUnnamedParams`
+type UnnamedInterface interface {
+	Get(context.Context, string) (string, error)
+	Printf(string, ...any)
+}
+
+// ContextInterface is used by `TestIgnoreContext`
+type ContextInterface interface {
+	Get(ctx context.Context, key string) (string, error)
+}
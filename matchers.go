@@ -0,0 +1,76 @@
+package ut
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Matcher may be passed to AddCall in place of a parameter value. Rather than
+// comparing the actual parameter with a value, the tracker asks the Matcher
+// whether the parameter is acceptable.
+type Matcher interface {
+	// Match returns true if the actual parameter is acceptable
+	Match(actual any) bool
+	// String describes what the Matcher accepts. It is used in failure
+	// messages
+	String() string
+}
+
+// matcher is a simple implementation of Matcher
+type matcher struct {
+	desc  string
+	match func(actual any) bool
+}
+
+func (m matcher) Match(actual any) bool { return m.match(actual) }
+func (m matcher) String() string        { return m.desc }
+
+// AnyContext matches any context.Context parameter
+func AnyContext() Matcher {
+	return matcher{
+		desc: "AnyContext()",
+		match: func(actual any) bool {
+			_, ok := actual.(context.Context)
+			return ok
+		},
+	}
+}
+
+// ContextWithValue matches a context.Context parameter that carries value v
+// for key
+func ContextWithValue(key, v any) Matcher {
+	return matcher{
+		desc: fmt.Sprintf("ContextWithValue(%#v, %#v)", key, v),
+		match: func(actual any) bool {
+			ctx, ok := actual.(context.Context)
+			return ok && reflect.DeepEqual(ctx.Value(key), v)
+		},
+	}
+}
+
+// ContextHasDeadline matches a context.Context parameter that has a deadline
+func ContextHasDeadline() Matcher {
+	return matcher{
+		desc: "ContextHasDeadline()",
+		match: func(actual any) bool {
+			ctx, ok := actual.(context.Context)
+			if !ok {
+				return false
+			}
+			_, ok = ctx.Deadline()
+			return ok
+		},
+	}
+}
+
+// withoutContexts returns params with any context.Context parameters removed
+func withoutContexts(params []any) []any {
+	out := make([]any, 0, len(params))
+	for _, p := range params {
+		if _, ok := p.(context.Context); !ok {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package ut
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/philpearl/ut/uttest"
+)
+
+type ctxKey struct{}
+
+func TestContextMatchers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), ctxKey{}, "cheese"), time.Hour)
+	defer cancel()
+
+	m := &MockSleeper{NewCallRecords(t)}
+	m.AddCall("Sleep", AnyContext(), time.Second).SetReturns(nil)
+	m.AddCall("Sleep", ContextWithValue(ctxKey{}, "cheese"), time.Second).SetReturns(nil)
+	m.AddCall("Sleep", ContextHasDeadline(), time.Second).SetReturns(nil)
+
+	m.Sleep(context.Background(), time.Second)
+	m.Sleep(ctx, time.Second)
+	m.Sleep(ctx, time.Second)
+
+	m.AssertDone()
+}
+
+func TestContextMatchersFail(t *testing.T) {
+	tests := []struct {
+		name    string
+		matcher Matcher
+		param   any
+	}{
+		{name: "AnyContext", matcher: AnyContext(), param: "not a context"},
+		{name: "ContextWithValue", matcher: ContextWithValue(ctxKey{}, "cheese"), param: context.Background()},
+		{name: "ContextHasDeadline", matcher: ContextHasDeadline(), param: context.Background()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ft := uttest.NewTB(t)
+			m := NewCallRecords(ft)
+			m.AddCall("Sleep", test.matcher)
+			m.TrackCall("Sleep", test.param)
+
+			if !ft.Failed() {
+				t.Fatal("expected the test to fail")
+			}
+		})
+	}
+}
+
+func TestIgnoreContext(t *testing.T) {
+	m := &MockSleeper{NewCallRecords(t).SetIgnoreContext(true)}
+	m.AddCall("Sleep", time.Second).SetReturns(nil)
+	m.AddCall("Sleep", ContextWithValue(ctxKey{}, "cheese"), time.Minute).SetReturns(nil)
+
+	m.Sleep(context.Background(), time.Second)
+	m.Sleep(context.WithValue(context.Background(), ctxKey{}, "cheese"), time.Minute)
+
+	m.AssertDone()
+}
@@ -0,0 +1,125 @@
+package ut
+
+import (
+	"reflect"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Option configures a CallTracker as it is created by NewCallRecords.
+// Generated mock constructors accept Options and pass them on.
+type Option func(o *options)
+
+type options struct {
+	name     string
+	mockType string
+	tracker  CallTracker
+	// configure change the behaviour of the tracker. They are applied in
+	// order once it is created.
+	configure []func(cr *callRecords)
+}
+
+// Strict makes the tracker fail the test immediately if an unexpected call
+// is made. This is the default. See StrictMode.
+func Strict() Option {
+	return func(o *options) {
+		o.configure = append(o.configure, func(cr *callRecords) { cr.mode = StrictMode })
+	}
+}
+
+// Lenient makes the tracker log unexpected calls and return default values
+// for them. See LenientMode and SetDefault().
+func Lenient() Option {
+	return func(o *options) {
+		o.configure = append(o.configure, func(cr *callRecords) { cr.mode = LenientMode })
+	}
+}
+
+// Recording makes the tracker quietly record calls that don't match an
+// expectation and return default values for them, rather than failing the
+// test. Use Verify to check the calls afterwards. See RecordingMode.
+func Recording() Option {
+	return func(o *options) {
+		o.configure = append(o.configure, func(cr *callRecords) { cr.mode = RecordingMode })
+	}
+}
+
+// Unordered allows the expected calls to be made in any order. Each call is
+// checked against the first outstanding expectation for the method whose
+// parameters match. If none match it is checked against the first
+// outstanding expectation for the method, and fails.
+func Unordered() Option {
+	return func(o *options) {
+		o.configure = append(o.configure, func(cr *callRecords) { cr.unordered = true })
+	}
+}
+
+// WithCmpOptions makes the tracker compare expected and actual parameters
+// with cmp.Equal and opts rather than with reflect.DeepEqual. Failure
+// messages then include a diff of each mismatched parameter.
+//
+//	m := NewMockStore(t, ut.WithCmpOptions(cmpopts.IgnoreFields(Record{}, "Updated")))
+func WithCmpOptions(opts ...cmp.Option) Option {
+	return func(o *options) {
+		o.configure = append(o.configure, func(cr *callRecords) { cr.cmpOptions = append(cr.cmpOptions, opts...) })
+	}
+}
+
+// WithName names the mock the tracker belongs to. Failure messages, the call
+// history and traces include the name, so name mocks of the same type to
+// tell them apart. Generated mocks are named after their type unless this
+// option is given. When a tracker is shared via WithTracker the name tells
+// the mocks apart, so give mocks of the same type different names.
+func WithName(name string) Option {
+	return func(o *options) { o.name = name }
+}
+
+// WithMockType gives the type of the mock the tracker belongs to. Failure
+// messages show it alongside the mock's name. Generated mocks set it, and
+// use it as the name unless WithName is given.
+func WithMockType(mockType string) Option {
+	return func(o *options) { o.mockType = mockType }
+}
+
+// WithTracker makes the mock use the state of tracker rather than a tracker
+// of its own, so that the expected calls to several mocks are checked in a
+// single sequence. tracker may be a tracker created by NewCallRecords or a
+// mock that contains one. Options that change the behaviour of the tracker,
+// such as Lenient(), apply to the shared tracker and so to all its mocks.
+//
+// Method names are prefixed with the name of the mock and a "." in the shared
+// tracker, so expectations added to the shared tracker directly, and failure
+// messages, use names like "MockDB.Begin".
+//
+//	shared := ut.NewCallRecords(t)
+//	db := NewMockDB(t, ut.WithTracker(shared))
+//	cache := NewMockCache(t, ut.WithTracker(shared))
+//	db.AddCall("Begin")
+//	cache.AddCall("Invalidate", "key")
+func WithTracker(tracker CallTracker) Option {
+	return func(o *options) { o.tracker = tracker }
+}
+
+// trackerOf finds the callRecords within ct, which may be a mock that embeds
+// a CallTracker
+func trackerOf(ct CallTracker) (*callRecords, bool) {
+	switch ct := ct.(type) {
+	case *callRecords:
+		return ct, true
+	case *Expectation:
+		return ct.cr, true
+	}
+	v := reflect.Indirect(reflect.ValueOf(ct))
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	f := v.FieldByName("CallTracker")
+	if !f.IsValid() || !f.CanInterface() {
+		return nil, false
+	}
+	inner, ok := f.Interface().(CallTracker)
+	if !ok || inner == nil {
+		return nil, false
+	}
+	return trackerOf(inner)
+}
@@ -0,0 +1,168 @@
+package ut
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/philpearl/ut/uttest"
+)
+
+func TestWithTracker(t *testing.T) {
+	shared := NewCallRecords(t)
+	getter := &MockGetter{NewCallRecords(t, WithName("getter"), WithTracker(shared))}
+	reader := &MockReader{NewCallRecords(t, WithName("reader"), WithTracker(shared))}
+
+	getter.AddCall("Get", "a").SetReturns("apple", nil)
+	reader.AddCall("Read", []byte("hat")).SetReturns(3, nil)
+	shared.AddCall("getter.Get", "b").SetReturns("banana", nil)
+
+	getter.Get("a")
+	reader.Read([]byte("hat"))
+	if v, _ := getter.Get("b"); v != "banana" {
+		t.Fatalf("expected banana, have %q", v)
+	}
+	shared.AssertDone()
+}
+
+func TestWithTrackerOrder(t *testing.T) {
+	ft := uttest.ExpectFailure(t, func(tb testing.TB) {
+		shared := NewCallRecords(tb)
+		getter := &MockGetter{NewCallRecords(tb, WithName("getter"), WithTracker(shared))}
+		reader := &MockReader{NewCallRecords(tb, WithName("reader"), WithTracker(shared))}
+
+		getter.AddCall("Get", "a").SetReturns("apple", nil)
+		reader.AddCall("Read", []byte("hat")).SetReturns(3, nil)
+
+		reader.TrackCall("Read", []byte("hat"))
+	})
+
+	if !slices.Contains(ft.Logs(), `reader: Expected call to getter.Get("a")`) {
+		t.Fatalf("expected the calls to be out of order. %q", ft.Logs())
+	}
+}
+
+func TestWithTrackerMock(t *testing.T) {
+	getter := &MockGetter{NewCallRecords(t)}
+	other := &MockGetter{NewCallRecords(t, WithName("other"), WithTracker(getter))}
+
+	other.RecordCall("Get", "apple", nil)
+	if _, ok := getter.GetRecordedParams("other.Get"); !ok {
+		t.Fatal("expected the recording to be in the shared tracker")
+	}
+}
+
+func TestLenient(t *testing.T) {
+	ft := uttest.NewTB(t)
+	getter := &MockGetter{NewCallRecords(ft, Lenient())}
+	getter.SetDefault("Get", "", nil)
+
+	getter.Get("a")
+	if ft.Failed() {
+		t.Fatalf("unexpected failure. %q", ft.Logs())
+	}
+}
+
+func TestStrict(t *testing.T) {
+	ft := uttest.Run(t, func(tb testing.TB) {
+		reader := &MockReader{NewCallRecords(tb, Lenient(), Strict())}
+		reader.Read([]byte("hat"))
+	})
+	if !ft.Stopped() {
+		t.Fatal("expected the unexpected call to stop the test")
+	}
+}
+
+func TestUnordered(t *testing.T) {
+	getter := &MockGetter{NewCallRecords(t, Unordered())}
+	getter.AddCall("Get", "a").SetReturns("apple", nil)
+	getter.AddCall("Get", "b").SetReturns("banana", nil).Times(2)
+	getter.AddCall("Get", "c").SetReturns("cherry", nil)
+
+	for _, key := range []string{"b", "c", "a", "b"} {
+		v, _ := getter.Get(key)
+		if !strings.HasPrefix(v, key) {
+			t.Fatalf("unexpected return %q for %q", v, key)
+		}
+	}
+	getter.AssertDone()
+}
+
+func TestUnorderedMismatch(t *testing.T) {
+	ft := uttest.NewTB(t)
+	getter := &MockGetter{NewCallRecords(ft, Unordered())}
+	getter.AddCall("Get", "a").SetReturns("apple", nil)
+	getter.AddCall("Get", "b").SetReturns("banana", nil)
+
+	getter.Get("b")
+	if ft.Failed() {
+		t.Fatalf("unexpected failure. %q", ft.Logs())
+	}
+	getter.Get("c")
+	if !ft.Failed() {
+		t.Fatal("expected the test to fail")
+	}
+	if !slices.Contains(ft.Logs(), `  expected "a" (string)`) {
+		t.Fatalf("expected the call to be checked against the outstanding expectation. %q", ft.Logs())
+	}
+}
+
+func TestWithCmpOptions(t *testing.T) {
+	ft := uttest.NewTB(t)
+	ignoreCase := cmp.Comparer(strings.EqualFold)
+	getter := &MockGetter{NewCallRecords(ft, WithCmpOptions(ignoreCase))}
+	getter.AddCall("Get", "apple").SetReturns("apple", nil)
+	getter.AddCall("Get", "banana").SetReturns("banana", nil)
+
+	getter.Get("APPLE")
+	if ft.Failed() {
+		t.Fatalf("unexpected failure. %q", ft.Logs())
+	}
+	getter.Get("cherry")
+	if !ft.Failed() {
+		t.Fatal("expected the test to fail")
+	}
+	if !slices.ContainsFunc(ft.Logs(), func(l string) bool { return strings.HasPrefix(l, "  diff (-expected +got):") }) {
+		t.Fatalf("expected a diff. %q", ft.Logs())
+	}
+}
+
+func TestNamedFailures(t *testing.T) {
+	ft := uttest.NewTB(t)
+	primary := &MockGetter{NewCallRecords(ft, WithName("primary"), WithMockType("MockGetter"))}
+	replica := &MockGetter{NewCallRecords(ft, WithName("replica"), WithMockType("MockGetter"))}
+	primary.AddCall("Get", "a").SetReturns("apple", nil)
+	replica.AddCall("Get", "b").SetReturns("banana", nil)
+
+	primary.Get("a")
+	primary.AssertDone()
+	replica.AssertDone()
+
+	if len(ft.Errors()) != 1 || ft.Errors()[0] != "replica (MockGetter): Only 0 of 1 expected calls made. Missed calls to Get" {
+		t.Fatalf("errors not as expected. %q", ft.Errors())
+	}
+	for _, l := range ft.Logs() {
+		if !strings.HasPrefix(l, "replica (MockGetter): ") {
+			t.Fatalf("log line not labelled with the mock. %q", l)
+		}
+	}
+
+	tr, err := primary.Trace(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc := tr.Calls[0]; tc.Mock != "primary" || tc.MockType != "MockGetter" {
+		t.Fatalf("call not labelled with the mock. %#v", tc)
+	}
+	if tc := tr.Expected[0]; tc.Mock != "primary" || tc.MockType != "MockGetter" {
+		t.Fatalf("expectation not labelled with the mock. %#v", tc)
+	}
+}
+
+func TestMockTypeIsDefaultName(t *testing.T) {
+	ct := NewCallRecords(t, WithMockType("MockGetter")).(*callRecords)
+	if l := ct.label(); l != "MockGetter" {
+		t.Fatalf("unexpected label %q", l)
+	}
+}
@@ -0,0 +1,225 @@
+package ut
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// utDir is the directory containing this package's source
+var utDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// label identifies the mock in failure messages by its name and type, as far
+// as they are known
+func (cr *callRecords) label() string {
+	switch {
+	case cr.name == "":
+		return cr.mockType
+	case cr.mockType == "" || cr.mockType == cr.name:
+		return cr.name
+	default:
+		return cr.name + " (" + cr.mockType + ")"
+	}
+}
+
+// logf logs a message about the mock, labelled with its name and type
+func (cr *callRecords) logf(format string, args ...any) {
+	cr.t.Helper()
+	cr.t.Logf("%s", cr.labelled(fmt.Sprintf(format, args...)))
+}
+
+// errorf reports a failure of the mock, labelled with its name and type
+func (cr *callRecords) errorf(format string, args ...any) {
+	cr.t.Helper()
+	cr.t.Errorf("%s", cr.labelled(fmt.Sprintf(format, args...)))
+}
+
+func (cr *callRecords) labelled(msg string) string {
+	if label := cr.label(); label != "" {
+		return label + ": " + msg
+	}
+	return msg
+}
+
+// showLocations logs where the expectation a call failed against was
+// declared, if there was one, and where in the code under test the call was
+// made. declared is empty if there was no expectation.
+func (cr *callRecords) showLocations(declared string) {
+	cr.t.Helper()
+	if declared != "" {
+		cr.logf("  expected at %s", declared)
+	}
+	cr.logf("  called from %s", callSite())
+}
+
+// showClosest logs the outstanding expectations for a call to the named
+// method, closest match first, showing which parameters differ from the
+// call. It logs nothing unless there is an outstanding expectation other than
+// e, the one the call was checked against. The lock must be held.
+func (cr *callRecords) showClosest(e *callRecord, name string, params []any) {
+	cr.t.Helper()
+	type candidate struct {
+		call   *callRecord
+		actual []any
+		// matched is the number of matching parameters, or -1 if the number
+		// of parameters is wrong
+		matched int
+		diffs   []int
+	}
+	var candidates []candidate
+	others := false
+	for _, call := range cr.calls[cr.current:] {
+		if !call.outstanding() || call.name != name {
+			continue
+		}
+		others = others || call != e
+		c := candidate{call: call, actual: params, matched: -1}
+		if cr.ignoreContext && len(c.actual) > len(call.params) {
+			c.actual = withoutContexts(c.actual)
+		}
+		if len(c.actual) == len(call.params) {
+			c.matched = 0
+			for i, ap := range c.actual {
+				if cr.paramMatches(call.params[i], ap) {
+					c.matched++
+				} else {
+					c.diffs = append(c.diffs, i)
+				}
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	if !others {
+		return
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].matched > candidates[j].matched })
+
+	cr.logf("Outstanding expectations for %s, closest first:", name)
+	for _, c := range candidates {
+		desc := name + cr.formatter.FormatList(c.call.params)
+		if c.matched < 0 {
+			cr.logf("  %s expected at %s has %d parameters, not %d", desc, c.call.declared, len(c.call.params), len(c.actual))
+			continue
+		}
+		cr.logf("  %s expected at %s: %d of %d parameters match", desc, c.call.declared, c.matched, len(c.actual))
+		for _, i := range c.diffs {
+			cr.logf("    parameter %d: expected %s, got %s", i, cr.formatter.formatParam(c.call.params[i]), cr.formatter.Format(c.actual[i]))
+		}
+	}
+}
+
+// paramsMatch returns true if the parameters of a call match those of the
+// expectation e. The lock must be held.
+func (cr *callRecords) paramsMatch(e *callRecord, params []any) bool {
+	if cr.ignoreContext && len(params) > len(e.params) {
+		params = withoutContexts(params)
+	}
+	if len(params) != len(e.params) {
+		return false
+	}
+	for i, ap := range params {
+		if !cr.paramMatches(e.params[i], ap) {
+			return false
+		}
+	}
+	return true
+}
+
+// paramMatches returns true if the actual parameter ap matches the expected
+// parameter ep. Function matchers can't be asked without side effects, so
+// are assumed to match.
+func (cr *callRecords) paramMatches(ep, ap any) bool {
+	switch ep := ep.(type) {
+	case func(actual any):
+		return true
+	case Matcher:
+		return ep.Match(ap)
+	default:
+		return cr.equal(ep, ap)
+	}
+}
+
+// equal compares an expected parameter with an actual one, using the options
+// given by WithCmpOptions() if there are any
+func (cr *callRecords) equal(ep, ap any) bool {
+	if cr.cmpOptions != nil {
+		return cmp.Equal(ep, ap, cr.cmpOptions...)
+	}
+	return reflect.DeepEqual(ep, ap)
+}
+
+// callerLocation returns the file and line of the code that called into the
+// tracker. It skips over internal frames, and over the methods generated mocks
+// use to pass setup calls on to the tracker.
+func callerLocation() string {
+	frames := callerFrames()
+	for {
+		f, more := frames.Next()
+		if !isInternal(f) && !isSetupForwarder(f.Function) {
+			return location(f)
+		}
+		if !more {
+			return "unknown location"
+		}
+	}
+}
+
+// callSite returns the location of the code that called a mock method. The
+// first frame outside the tracker is the mock method itself, so this is the
+// frame after it.
+func callSite() string {
+	frames := callerFrames()
+	mock := false
+	for {
+		f, more := frames.Next()
+		if !isInternal(f) {
+			if mock {
+				return fmt.Sprintf("%s (%s)", location(f), funcName(f))
+			}
+			mock = true
+		}
+		if !more {
+			return "unknown location"
+		}
+	}
+}
+
+func callerFrames() *runtime.Frames {
+	pc := make([]uintptr, 64)
+	n := runtime.Callers(3, pc)
+	return runtime.CallersFrames(pc[:n])
+}
+
+// isInternal returns true for frames in this package (apart from its tests),
+// and in the runtime and testing packages. These are of no interest when
+// reporting failures.
+func isInternal(f runtime.Frame) bool {
+	if filepath.Dir(f.File) == utDir && !strings.HasSuffix(f.File, "_test.go") {
+		return true
+	}
+	return strings.HasPrefix(f.Function, "runtime.") || strings.HasPrefix(f.Function, "testing.")
+}
+
+// isSetupForwarder returns true for the methods generated mocks use to pass
+// setup calls on to the tracker
+func isSetupForwarder(function string) bool {
+	return strings.HasSuffix(function, ").AddCall") || strings.HasSuffix(function, ").SetReturns")
+}
+
+func location(f runtime.Frame) string {
+	return fmt.Sprintf("%s:%d", filepath.Base(f.File), f.Line)
+}
+
+// funcName returns the function name of the frame qualified by the package
+// name rather than the full import path
+func funcName(f runtime.Frame) string {
+	return f.Function[strings.LastIndex(f.Function, "/")+1:]
+}
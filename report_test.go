@@ -0,0 +1,90 @@
+package ut
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/ut/uttest"
+)
+
+// line returns the line number of the code that called it
+func line() int {
+	_, _, l, _ := runtime.Caller(1)
+	return l
+}
+
+func TestReportLocations(t *testing.T) {
+	var declared int
+	ft := uttest.ExpectFailure(t, func(tb testing.TB) {
+		m := &MockReader{NewCallRecords(tb)}
+		m.AddCall("Read", []byte("hat")).SetReturns(3, nil)
+		declared = line() - 1
+
+		UnderTest(m)
+	})
+
+	exp := fmt.Sprintf("  expected at report_test.go:%d", declared)
+	if !slices.Contains(ft.Logs(), exp) {
+		t.Errorf("expected %q in logs. %q", exp, ft.Logs())
+	}
+	calledFrom := func(l string) bool {
+		return strings.HasPrefix(l, "  called from callrecord_test.go:") && strings.HasSuffix(l, " (ut.UnderTest)")
+	}
+	if !slices.ContainsFunc(ft.Logs(), calledFrom) {
+		t.Errorf("expected call site in logs. %q", ft.Logs())
+	}
+}
+
+func TestReportMissed(t *testing.T) {
+	ft := uttest.NewTB(t)
+	m := &MockReader{NewCallRecords(ft)}
+	m.AddCall("Read", []byte("hat")).SetReturns(3, nil)
+	declared := line() - 1
+	m.AssertDone()
+
+	exp := fmt.Sprintf("  Read([]byte{0x68, 0x61, 0x74}) expected at report_test.go:%d", declared)
+	if !slices.Contains(ft.Logs(), exp) {
+		t.Errorf("expected %q in logs. %q", exp, ft.Logs())
+	}
+}
+
+func TestReportClosest(t *testing.T) {
+	var declared int
+	ft := uttest.ExpectFailure(t, func(tb testing.TB) {
+		m := &MockGetter{NewCallRecords(tb)}
+		m.AddCall("Get", "user/2").SetReturns("", nil)
+		m.AddCall("Get", "user/1").SetReturns("", nil)
+		declared = line() - 1
+		m.AddCall("Get", "user/01", "extra").SetReturns("", nil)
+
+		m.Get("user/1")
+	})
+
+	exp := []string{
+		"Outstanding expectations for Get, closest first:",
+		fmt.Sprintf(`  Get("user/1") expected at report_test.go:%d: 1 of 1 parameters match`, declared),
+		fmt.Sprintf(`  Get("user/2") expected at report_test.go:%d: 0 of 1 parameters match`, declared-1),
+		`    parameter 0: expected "user/2", got "user/1"`,
+		fmt.Sprintf(`  Get("user/01", "extra") expected at report_test.go:%d has 2 parameters, not 1`, declared+2),
+	}
+	i := slices.Index(ft.Logs(), exp[0])
+	if i < 0 || len(ft.Logs()) < i+len(exp) || !slices.Equal(ft.Logs()[i:i+len(exp)], exp) {
+		t.Fatalf("closest matches not as expected. %q", ft.Logs())
+	}
+}
+
+func TestReportClosestOnlyExpectation(t *testing.T) {
+	ft := uttest.ExpectFailure(t, func(tb testing.TB) {
+		m := &MockGetter{NewCallRecords(tb)}
+		m.AddCall("Get", "user/2").SetReturns("", nil)
+
+		m.Get("user/1")
+	})
+
+	if slices.ContainsFunc(ft.Logs(), func(l string) bool { return strings.HasPrefix(l, "Outstanding") }) {
+		t.Fatalf("closest matches should not be listed for a single expectation. %q", ft.Logs())
+	}
+}
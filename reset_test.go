@@ -0,0 +1,95 @@
+package ut
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/philpearl/ut/uttest"
+)
+
+func TestReset(t *testing.T) {
+	m := &MockGetter{NewCallRecords(t)}
+	m.SetMode(LenientMode)
+
+	for _, key := range []string{"a", "b", "c"} {
+		m.Reset()
+		m.AddCall("Get", key).SetReturns(key+key, nil)
+		m.FailNth("Get", 2, errors.New("fail"))
+
+		if v, err := m.Get(key); v != key+key || err != nil {
+			t.Fatalf("unexpected return %q, %v", v, err)
+		}
+		m.AssertDone()
+	}
+
+	tr, err := m.Trace(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Expected) != 1 || len(tr.Calls) != 1 {
+		t.Fatalf("expected only the last row in the trace. %#v", tr)
+	}
+
+	// The mode is kept, so this unexpected call doesn't fail the test
+	m.SetDefault("Get", "", nil)
+	m.Get("d")
+}
+
+func TestResetDiscardsExpectations(t *testing.T) {
+	ft := uttest.NewTB(t)
+	m := &MockGetter{NewCallRecords(ft)}
+	m.AddCall("Get", "a").SetReturns("apple", nil)
+	m.RecordCall("Put")
+	m.Reset()
+	m.AssertDone()
+
+	if ft.Failed() {
+		t.Fatalf("discarded expectations should not be asserted. %q", ft.Errors())
+	}
+	if _, ok := m.GetRecordedParams("Put"); ok {
+		t.Fatal("recording should be discarded")
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	m := &MockGetter{NewCallRecords(t)}
+	m.RecordCall("Put")
+
+	m.AddCall("Get", "a").SetReturns("apple", nil)
+	m.Get("a")
+	m.Checkpoint()
+
+	m.AddCall("Get", "b").SetReturns("banana", nil)
+	if v, _ := m.Get("b"); v != "banana" {
+		t.Fatalf("expected banana, have %q", v)
+	}
+	m.Checkpoint()
+
+	if _, ok := m.GetRecordedParams("Put"); !ok {
+		t.Fatal("recording should be kept")
+	}
+	tr, err := m.Trace(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Calls) != 2 {
+		t.Fatalf("expected both calls in the history. %#v", tr.Calls)
+	}
+}
+
+func TestCheckpointMissed(t *testing.T) {
+	ft := uttest.NewTB(t)
+	m := &MockGetter{NewCallRecords(ft)}
+	m.AddCall("Get", "a").SetReturns("apple", nil)
+	m.Checkpoint()
+
+	if !ft.Failed() {
+		t.Fatal("expected the test to fail")
+	}
+
+	// The missed expectation is not carried into the next phase
+	m.AssertDone()
+	if len(ft.Errors()) != 1 {
+		t.Fatalf("missed expectation reported again. %q", ft.Errors())
+	}
+}
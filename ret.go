@@ -0,0 +1,46 @@
+package ut
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// Ret is a utility function for returning values from mocked methods. It
+// returns the ith value from r, the values returned by TrackCall, as a T. If
+// the value is nil, or r has fewer than i+1 values, it returns the zero value
+// of T. If the value is not a T, Ret panics with a message naming the mocked
+// method and the position of the return value.
+//
+//	r := m.TrackCall("Read", p)
+//	return ut.Ret[int](r, 0), ut.Ret[error](r, 1)
+func Ret[T any](r []any, i int) T {
+	var zero T
+	return retOr(r, i, zero)
+}
+
+// RetOr is like Ret, but returns def rather than the zero value if the value
+// is nil or missing.
+func RetOr[T any](r []any, i int, def T) T {
+	return retOr(r, i, def)
+}
+
+func retOr[T any](r []any, i int, def T) T {
+	if i >= len(r) || r[i] == nil {
+		return def
+	}
+	v, ok := r[i].(T)
+	if !ok {
+		panic(fmt.Sprintf("ut: return value %d of %s is %#v (%T), which is not a %s", i, callerName(3), r[i], r[i], reflect.TypeFor[T]()))
+	}
+	return v
+}
+
+// callerName returns the name of the function skip frames up the stack
+func callerName(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return "unknown method"
+	}
+	return runtime.FuncForPC(pc).Name()
+}
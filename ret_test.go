@@ -0,0 +1,44 @@
+package ut
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRet(t *testing.T) {
+	errFail := errors.New("fail")
+	r := []any{37, nil, errFail}
+
+	if v := Ret[int](r, 0); v != 37 {
+		t.Errorf("expected 37, have %d", v)
+	}
+	if v := Ret[string](r, 1); v != "" {
+		t.Errorf("expected empty string, have %q", v)
+	}
+	if v := Ret[error](r, 2); v != errFail {
+		t.Errorf("expected error, have %v", v)
+	}
+	if v := Ret[int](r, 3); v != 0 {
+		t.Errorf("expected 0 for missing value, have %d", v)
+	}
+	if v := Ret[int](nil, 0); v != 0 {
+		t.Errorf("expected 0 for nil returns, have %d", v)
+	}
+	if v := RetOr(r, 1, "default"); v != "default" {
+		t.Errorf("expected default, have %q", v)
+	}
+	if v := RetOr(r, 0, 5); v != 37 {
+		t.Errorf("expected 37, have %d", v)
+	}
+}
+
+func TestRetWrongType(t *testing.T) {
+	defer func() {
+		msg, _ := recover().(string)
+		exp := "ut: return value 0 of github.com/philpearl/ut.TestRetWrongType is 37 (int64), which is not a int"
+		if msg != exp {
+			t.Fatalf("panic message not as expected. Have %q", msg)
+		}
+	}()
+	Ret[int]([]any{int64(37)}, 0)
+}
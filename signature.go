@@ -0,0 +1,212 @@
+package ut
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Method describes the signature of a mocked method. genmock generates a
+// Method for each method of a mock and passes them to SetMethods so that the
+// tracker can check expectations as they are set up, rather than the mock
+// failing in a confusing way when it is called.
+type Method struct {
+	Name string
+	// Params are the types of the method's parameters. If the method is
+	// variadic the last is a slice type, as with reflect.Type.In
+	Params []reflect.Type
+	// Variadic is true if the method's last parameter is variadic
+	Variadic bool
+	// Returns are the types of the values the method returns
+	Returns []reflect.Type
+}
+
+var (
+	errorType   = reflect.TypeFor[error]()
+	contextType = reflect.TypeFor[context.Context]()
+)
+
+func (cr *callRecords) SetMethods(methods ...Method) CallTracker {
+	cr.Lock()
+	defer cr.Unlock()
+	if cr.methods == nil {
+		cr.methods = make(map[string]Method, len(methods))
+	}
+	for _, m := range methods {
+		cr.methods[cr.qualify(m.Name)] = m
+	}
+	return cr
+}
+
+// method returns the signature of the named method, if it is known. If the
+// signatures of the mock's methods are known but the method is not one of
+// them the test fails. The lock must be held.
+func (cr *callRecords) method(name string) (Method, bool) {
+	m, ok := cr.methods[name]
+	if !ok && cr.hasMethods(name) {
+		mock := cr.label()
+		if mock == "" {
+			mock = "The mock"
+		}
+		cr.setupFailed("%s has no method %s", mock, strings.TrimPrefix(name, cr.prefix))
+	}
+	return m, ok
+}
+
+// hasMethods reports whether the signatures of the methods of the mock the
+// named method belongs to are known. In a shared tracker method names are
+// prefixed with the name of their mock, and each mock may or may not have
+// signatures.
+func (cr *callRecords) hasMethods(name string) bool {
+	prefix := name[:strings.LastIndex(name, ".")+1]
+	for known := range cr.methods {
+		if strings.HasPrefix(known, prefix) && !strings.Contains(known[len(prefix):], ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkParams checks that params are suitable expected parameters for the
+// named method, and fails the test if not. Parameters that are matchers are
+// not checked. The lock must be held.
+func (cr *callRecords) checkParams(name string, params []any) {
+	m, ok := cr.method(name)
+	if !ok {
+		return
+	}
+	types := m.Params
+	if cr.ignoreContext && !paramCountOK(m, types, len(params)) {
+		// The context parameters may have been left out
+		types = withoutContextTypes(types)
+	}
+	if !paramCountOK(m, types, len(params)) {
+		want := strconv.Itoa(len(types))
+		if m.Variadic {
+			want = "at least " + strconv.Itoa(len(types)-1)
+		}
+		cr.setupFailed("%s takes %s parameters %s, but %d given %s", name, want, typesToString(types), len(params), cr.formatter.FormatList(params))
+		return
+	}
+	for i, p := range params {
+		switch p.(type) {
+		case func(actual any), Matcher:
+			continue
+		}
+		typ := paramType(m, types, i)
+		if !assignable(p, typ) {
+			cr.setupFailed("%s parameter %d should be %s, but is %s (%T)", name, i, typ, cr.formatter.Format(p), p)
+			return
+		}
+	}
+}
+
+// paramCountOK returns true if n parameters is the right number for a method
+// with parameters types
+func paramCountOK(m Method, types []reflect.Type, n int) bool {
+	if m.Variadic {
+		return n >= len(types)-1
+	}
+	return n == len(types)
+}
+
+// paramType returns the type of parameter i. Mocks pass each variadic
+// argument as a separate parameter, so these have the element type of the
+// variadic slice.
+func paramType(m Method, types []reflect.Type, i int) reflect.Type {
+	if m.Variadic && i >= len(types)-1 {
+		return types[len(types)-1].Elem()
+	}
+	return types[i]
+}
+
+func withoutContextTypes(types []reflect.Type) []reflect.Type {
+	out := make([]reflect.Type, 0, len(types))
+	for _, t := range types {
+		if t != contextType {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// assignable returns true if v could be compared equal to a parameter of type
+// typ. Parameters are compared with reflect.DeepEqual, so for concrete types
+// the type must match exactly. nil is acceptable for types that can be nil.
+func assignable(v any, typ reflect.Type) bool {
+	if v == nil {
+		switch typ.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+			return true
+		}
+		return false
+	}
+	return returnable(v, typ)
+}
+
+// checkReturns checks that returns are suitable return values for the named
+// method, and fails the test if not. No values is always acceptable, as mocks
+// convert these to zero values. The lock must be held.
+func (cr *callRecords) checkReturns(name string, returns []any) {
+	m, ok := cr.method(name)
+	if !ok || len(returns) == 0 {
+		return
+	}
+	if len(returns) != len(m.Returns) {
+		cr.setupFailed("%s returns %s, but %d return values given %s", name, typesToString(m.Returns), len(returns), cr.formatter.FormatList(returns))
+		return
+	}
+	for i, r := range returns {
+		if !returnable(r, m.Returns[i]) {
+			cr.setupFailed("%s return value %d should be %s, but is %s (%T)", name, i, m.Returns[i], cr.formatter.Format(r), r)
+			return
+		}
+	}
+}
+
+// returnable returns true if mocks can return v as a value of type typ. Mocks
+// convert values using a type assertion, so for concrete types the type must
+// match exactly. nil is converted to the zero value of any type.
+func returnable(v any, typ reflect.Type) bool {
+	if v == nil {
+		return true
+	}
+	if typ.Kind() == reflect.Interface {
+		return reflect.TypeOf(v).Implements(typ)
+	}
+	return reflect.TypeOf(v) == typ
+}
+
+// errorReturn returns the index of the named method's error return value, if
+// the method is known and has one.
+func (cr *callRecords) errorReturn(name string) (int, bool) {
+	m, ok := cr.methods[name]
+	if !ok {
+		return 0, false
+	}
+	for i := len(m.Returns) - 1; i >= 0; i-- {
+		if m.Returns[i] == errorType {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// setupFailed reports a mistake in setting up expectations. The message
+// includes the location of the test code that made the mistake. The lock
+// must be held.
+func (cr *callRecords) setupFailed(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	cr.t.Logf("%s: %s", callerLocation(), cr.labelled(msg))
+	cr.failNow("%s", msg)
+}
+
+func typesToString(types []reflect.Type) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.String()
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
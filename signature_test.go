@@ -0,0 +1,151 @@
+package ut
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/ut/uttest"
+)
+
+var readerMethods = []Method{
+	{Name: "Read", Params: []reflect.Type{reflect.TypeFor[[]byte]()}, Returns: []reflect.Type{reflect.TypeFor[int](), reflect.TypeFor[error]()}},
+}
+
+func TestCheckReturns(t *testing.T) {
+	tests := []struct {
+		name    string
+		returns []any
+		fail    string
+	}{
+		{name: "ok", returns: []any{3, nil}},
+		{name: "error", returns: []any{0, io.EOF}},
+		{name: "none", returns: nil},
+		{name: "count", returns: []any{3}, fail: "Read returns (int, error), but 1 return values given (3)"},
+		{name: "type", returns: []any{int64(3), nil}, fail: "Read return value 0 should be int, but is 3 (int64)"},
+		{name: "not error", returns: []any{3, "oops"}, fail: `Read return value 1 should be error, but is "oops" (string)`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ft := uttest.Run(t, func(tb testing.TB) {
+				m := &MockReader{NewCallRecords(tb, WithSummary(SummaryNever)).SetMethods(readerMethods...)}
+				m.AddCall("Read", []byte("hat")).SetReturns(test.returns...)
+			})
+
+			if test.fail == "" {
+				if ft.Failed() {
+					t.Fatalf("unexpected failure. %v %v", ft.Logs(), ft.Errors())
+				}
+				return
+			}
+			if !ft.Stopped() {
+				t.Fatal("expected the test to stop")
+			}
+			if len(ft.Logs()) != 1 || !strings.HasPrefix(ft.Logs()[0], "signature_test.go:") || !strings.HasSuffix(ft.Logs()[0], test.fail) {
+				t.Fatalf("log not as expected. %q", ft.Logs())
+			}
+		})
+	}
+}
+
+func TestCheckParams(t *testing.T) {
+	methods := []Method{
+		{Name: "Get", Params: []reflect.Type{reflect.TypeFor[context.Context](), reflect.TypeFor[string]()}},
+		{Name: "Printf", Params: []reflect.Type{reflect.TypeFor[string](), reflect.TypeFor[[]any]()}, Variadic: true},
+		{Name: "Join", Params: []reflect.Type{reflect.TypeFor[[]string]()}, Variadic: true},
+		{Name: "Count", Params: []reflect.Type{reflect.TypeFor[int]()}},
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		params        []any
+		ignoreContext bool
+		fail          string
+	}{
+		{name: "ok", method: "Get", params: []any{context.Background(), "a"}},
+		{name: "matchers", method: "Get", params: []any{AnyContext(), func(actual any) {}}},
+		{name: "nil interface", method: "Get", params: []any{nil, "a"}},
+		{name: "count", method: "Get", params: []any{"a"}, fail: "Get takes 2 parameters (context.Context, string), but 1 given (\"a\")"},
+		{name: "type", method: "Get", params: []any{context.Background(), 1}, fail: "Get parameter 1 should be string, but is 1 (int)"},
+		{name: "nil int", method: "Count", params: []any{nil}, fail: "Count parameter 0 should be int, but is <nil> (<nil>)"},
+		{name: "ignore context", method: "Get", params: []any{"a"}, ignoreContext: true},
+		{name: "ignore context type", method: "Get", params: []any{1}, ignoreContext: true, fail: "Get parameter 0 should be string, but is 1 (int)"},
+		{name: "variadic none", method: "Printf", params: []any{"hat"}},
+		{name: "variadic some", method: "Printf", params: []any{"%s %d", "hat", 1}},
+		{name: "variadic missing", method: "Printf", params: nil, fail: "Printf takes at least 1 parameters (string, []interface {}), but 0 given ()"},
+		{name: "variadic only", method: "Join", params: []any{"a", "b"}},
+		{name: "variadic type", method: "Join", params: []any{"a", 1}, fail: "Join parameter 1 should be string, but is 1 (int)"},
+		{name: "unknown", method: "Gett", params: []any{"a"}, fail: "The mock has no method Gett"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ft := uttest.Run(t, func(tb testing.TB) {
+				ct := NewCallRecords(tb).SetMethods(methods...).SetIgnoreContext(test.ignoreContext)
+				ct.AddCall(test.method, test.params...)
+			})
+
+			if test.fail == "" {
+				if ft.Failed() {
+					t.Fatalf("unexpected failure. %v %v", ft.Logs(), ft.Errors())
+				}
+				return
+			}
+			if !ft.Stopped() {
+				t.Fatal("expected the test to stop")
+			}
+			if len(ft.Logs()) != 1 || !strings.HasSuffix(ft.Logs()[0], test.fail) {
+				t.Fatalf("log not as expected. %q", ft.Logs())
+			}
+		})
+	}
+}
+
+func TestUnknownMethodShared(t *testing.T) {
+	var failedEarly bool
+	ft := uttest.Run(t, func(tb testing.TB) {
+		shared := NewCallRecords(tb, WithSummary(SummaryNever))
+		reader := &MockReader{NewCallRecords(tb, WithName("reader"), WithTracker(shared)).SetMethods(readerMethods...)}
+		getter := &MockGetter{NewCallRecords(tb, WithName("getter"), WithTracker(shared))}
+
+		// The getter's signatures aren't known, so any method is accepted
+		getter.AddCall("Get", "a")
+		shared.AddCall("getter.Anything")
+		failedEarly = tb.Failed()
+		reader.AddCall("Reed", []byte("hat"))
+	})
+	if failedEarly {
+		t.Fatalf("unexpected failure. %q", ft.Logs())
+	}
+	if !ft.Stopped() || !strings.HasSuffix(ft.Logs()[0], "reader has no method Reed") {
+		t.Fatalf("expected the unknown method to fail. %q", ft.Logs())
+	}
+}
+
+func TestCheckReturnsRecordCall(t *testing.T) {
+	ft := uttest.ExpectFailure(t, func(tb testing.TB) {
+		m := &MockReader{NewCallRecords(tb).SetMethods(readerMethods...)}
+		m.RecordCall("Read", "three", nil)
+	})
+	if !ft.Stopped() {
+		t.Fatal("expected the test to stop")
+	}
+}
+
+func TestFailNthSignature(t *testing.T) {
+	errFail := errors.New("fail")
+	m := &MockReader{NewCallRecords(t).SetMethods(readerMethods...)}
+	m.AddCall("Read", []byte("hat"))
+	m.FailNth("Read", 1, errFail)
+
+	// No returns were set, but the signature tells us where the error goes
+	if r := m.TrackCall("Read", []byte("hat")); len(r) != 2 || r[0] != nil || r[1] != errFail {
+		t.Fatalf("unexpected return %v", r)
+	}
+	m.AssertDone()
+}
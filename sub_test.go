@@ -0,0 +1,46 @@
+package ut
+
+import (
+	"testing"
+
+	"github.com/philpearl/ut/uttest"
+)
+
+func TestSub(t *testing.T) {
+	m := &MockGetter{NewCallRecords(t)}
+	m.RecordCall("Get", "default", nil)
+
+	for _, key := range []string{"a", "b"} {
+		t.Run(key, func(t *testing.T) {
+			sub := &MockGetter{m.Sub(t)}
+			if v, _ := sub.Get("x"); v != "default" {
+				t.Fatalf("expected the parent's recording, have %q", v)
+			}
+			params, _ := sub.GetRecordedParams("Get")
+			if len(params) != 1 {
+				t.Fatalf("expected only this subtest's call to be recorded. %v", params)
+			}
+		})
+	}
+	if params, _ := m.GetRecordedParams("Get"); len(params) != 0 {
+		t.Fatalf("calls to the children should not be recorded by the parent. %v", params)
+	}
+}
+
+func TestSubVerified(t *testing.T) {
+	m := &MockGetter{NewCallRecords(t)}
+	m.AddCall("Get", "parent").SetReturns("p", nil)
+
+	ft := uttest.NewTB(t)
+	sub := &MockGetter{m.Sub(ft)}
+	sub.AddCall("Get", "child").SetReturns("c", nil)
+	ft.RunCleanups()
+
+	if len(ft.Errors()) != 1 || ft.Errors()[0] != "Only 0 of 1 expected calls made. Missed calls to Get" {
+		t.Fatalf("expected the child's missed call to be reported to the subtest. %q", ft.Errors())
+	}
+
+	// The parent's expectations are its own
+	m.Get("parent")
+	m.AssertDone()
+}
@@ -0,0 +1,117 @@
+package ut
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"text/tabwriter"
+)
+
+// SummaryMode controls when a tracker logs a summary of its expectations and
+// the calls made to it as the test ends.
+type SummaryMode int
+
+const (
+	// SummaryOnFailure logs the summary only if the test failed. This is
+	// the default.
+	SummaryOnFailure SummaryMode = iota
+	// SummaryVerbose logs the summary if the test failed or go test was run
+	// with -v.
+	SummaryVerbose
+	// SummaryAlways logs the summary whenever the test ends.
+	SummaryAlways
+	// SummaryNever never logs the summary.
+	SummaryNever
+)
+
+// WithSummary selects when the tracker logs a summary of its expectations and
+// calls as the test ends. See Summary().
+func WithSummary(mode SummaryMode) Option {
+	return func(o *options) {
+		o.configure = append(o.configure, func(cr *callRecords) { cr.summary = mode })
+	}
+}
+
+func (cr *callRecords) Summary() string {
+	cr.Lock()
+	defer cr.Unlock()
+	return cr.summaryLocked()
+}
+
+// summarise logs the summary when the test ends, if the summary mode asks
+// for it
+func (cr *callRecords) summarise() {
+	cr.Lock()
+	defer cr.Unlock()
+	switch cr.summary {
+	case SummaryNever:
+		return
+	case SummaryOnFailure:
+		if !cr.t.Failed() {
+			return
+		}
+	case SummaryVerbose:
+		if !cr.t.Failed() && !testing.Verbose() {
+			return
+		}
+	}
+	if summary := cr.summaryLocked(); summary != "" {
+		cr.logf("Summary of expectations and calls\n%s", summary)
+	}
+}
+
+// summaryLocked builds the summary table. It is empty if the tracker has no
+// expectations or recordings and no unexpected calls were made. The lock must
+// be held.
+func (cr *callRecords) summaryLocked() string {
+	if len(cr.calls) == 0 && len(cr.records) == 0 && len(cr.unexpected) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	if len(cr.calls) > 0 {
+		fmt.Fprintf(w, "EXPECTED\tTIMES\tMATCHED\tDECLARED AT\n")
+		for _, call := range cr.calls {
+			fmt.Fprintf(w, "%s%s\t%d\t%d\t%s\n", call.name, cr.formatter.FormatList(call.params), call.times, call.matched, call.declared)
+			for _, i := range call.history {
+				h := cr.history[i]
+				result := "matched"
+				if !h.Passed {
+					result = "did not match"
+				}
+				fmt.Fprintf(w, "  %s%s %s\t\t\t\n", h.Name, cr.formatter.FormatList(h.Params), result)
+			}
+		}
+	}
+	if len(cr.records) > 0 {
+		fmt.Fprintf(w, "RECORDED\tCALLS\t\t\n")
+		names := make([]string, 0, len(cr.records))
+		for name := range cr.records {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			record := cr.records[name]
+			fmt.Fprintf(w, "%s\t%d\t\t\n", name, len(record.params))
+			for _, params := range record.params {
+				fmt.Fprintf(w, "  %s%s\t\t\t\n", name, cr.formatter.FormatList(params))
+			}
+		}
+	}
+	if len(cr.unexpected) > 0 {
+		fmt.Fprintf(w, "UNEXPECTED\t\t\t\n")
+		for _, i := range cr.unexpected {
+			h := cr.history[i]
+			fmt.Fprintf(w, "  %s%s\t\t\t\n", h.Name, cr.formatter.FormatList(h.Params))
+		}
+	}
+	w.Flush()
+
+	// Rows with empty cells are padded with spaces, which we don't want
+	lines := strings.SplitAfter(b.String(), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \n") + "\n"
+	}
+	return strings.Join(lines[:len(lines)-1], "")
+}
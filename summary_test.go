@@ -0,0 +1,82 @@
+package ut
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/ut/uttest"
+)
+
+func TestSummary(t *testing.T) {
+	ft := uttest.NewTB(t)
+	getter := &MockGetter{NewCallRecords(ft, Lenient())}
+	getter.AddCall("Get", "a").SetReturns("apple", nil).Times(2)
+	declared := line() - 1
+	getter.AddCall("Get", "b").SetReturns("banana", nil)
+	getter.RecordCall("Put", "", nil)
+	getter.SetDefault("Delete", "", nil)
+
+	getter.Get("a")
+	getter.Get("c")
+	getter.TrackCall("Put", "x")
+	getter.TrackCall("Delete", "y")
+
+	exp := fmt.Sprintf(`EXPECTED                  TIMES  MATCHED  DECLARED AT
+Get("a")                  2      1        summary_test.go:%d
+  Get("a") matched
+  Get("c") did not match
+Get("b")                  1      0        summary_test.go:%d
+RECORDED                  CALLS
+Put                       1
+  Put("x")
+UNEXPECTED
+  Delete("y")
+`, declared, declared+2)
+	if s := getter.Summary(); s != exp {
+		t.Fatalf("summary not as expected.\n%s", s)
+	}
+}
+
+func TestSummaryOnFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   SummaryMode
+		fail   bool
+		logged bool
+	}{
+		{name: "on failure, passed", mode: SummaryOnFailure},
+		{name: "on failure, failed", mode: SummaryOnFailure, fail: true, logged: true},
+		{name: "always", mode: SummaryAlways, logged: true},
+		{name: "never", mode: SummaryNever, fail: true},
+		{name: "verbose, failed", mode: SummaryVerbose, fail: true, logged: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ft := uttest.NewTB(t)
+			getter := &MockGetter{NewCallRecords(ft, WithName("getter"), WithSummary(test.mode))}
+			getter.AddCall("Get", "a").SetReturns("apple", nil)
+			getter.Get("a")
+			if test.fail {
+				ft.Fail()
+			}
+			ft.RunCleanups()
+
+			logged := slices.ContainsFunc(ft.Logs(), func(l string) bool {
+				return strings.HasPrefix(l, "getter: Summary of expectations and calls\nEXPECTED")
+			})
+			if logged != test.logged {
+				t.Fatalf("summary logged %t, expected %t. %q", logged, test.logged, ft.Logs())
+			}
+		})
+	}
+}
+
+func TestSummaryEmpty(t *testing.T) {
+	ct := NewCallRecords(t)
+	if s := ct.Summary(); s != "" {
+		t.Fatalf("expected no summary, have %q", s)
+	}
+}
@@ -0,0 +1,183 @@
+package ut
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Call describes a call made to a mock
+type Call struct {
+	// Name is the name of the method called
+	Name string
+	// Params are the parameters passed to the method
+	Params []any
+	// Returns are the values the mock returned
+	Returns []any
+	// Passed is true if the call met an expectation or was recorded via
+	// RecordCall
+	Passed bool
+	// Panicked is true if the mock panicked rather than returning, in which
+	// case Panic is the value it panicked with
+	Panicked bool
+	Panic    any
+	// Mock is the name of the mock called, and MockType its type, if known
+	Mock     string
+	MockType string
+}
+
+// Trace is the serialisable form of the expectations set on a CallTracker and
+// the calls actually made to it. It is intended for tooling such as test
+// reports, and for diffing the interactions of one test run against another.
+//
+// The JSON schema is stable: new fields may be added, but existing fields will
+// not be renamed or removed.
+type Trace struct {
+	// Expected lists the expectations added via AddCall, in order. Passed
+	// indicates whether each expectation was met.
+	Expected []TraceCall `json:"expected"`
+	// Calls lists the calls made to the mock, in order. Passed indicates
+	// whether each call was expected.
+	Calls []TraceCall `json:"calls"`
+}
+
+// TraceCall is the serialisable form of an expectation or a call
+type TraceCall struct {
+	Method  string       `json:"method"`
+	Params  []TraceValue `json:"params"`
+	Returns []TraceValue `json:"returns"`
+	Passed  bool         `json:"passed"`
+	// Panic is the value the call panicked with, if it panicked
+	Panic *TraceValue `json:"panic,omitempty"`
+	// Mock is the name of the mock, and MockType its type, if known
+	Mock     string `json:"mock,omitempty"`
+	MockType string `json:"mockType,omitempty"`
+}
+
+// TraceValue is the serialisable form of a parameter or return value.
+type TraceValue struct {
+	// Type is the Go type of the value, as printed by %T. It is empty for nil
+	// values, "error" for any value implementing error and "context.Context"
+	// for contexts.
+	Type string `json:"type"`
+	// Value is the JSON encoding of the value.
+	Value json.RawMessage `json:"value"`
+}
+
+// ValueEncoder converts parameter and return values into their serialisable
+// form.
+type ValueEncoder func(v any) (TraceValue, error)
+
+// traceContext is the type recorded for context.Context values
+const traceContext = "context.Context"
+
+// DefaultValueEncoder is the ValueEncoder used when none is specified.
+//
+// Errors are encoded as their message. Contexts are encoded as null with the
+// type "context.Context", as their contents can't usefully be recorded.
+// Functions and channels are encoded as null as they have no useful JSON
+// form, and any other value that cannot be marshalled is encoded as a string
+// formatted with %v. A nil pointer to an error type is encoded as null with
+// its type.
+func DefaultValueEncoder(v any) (TraceValue, error) {
+	if v == nil {
+		return TraceValue{Value: json.RawMessage("null")}, nil
+	}
+	if err, ok := v.(error); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			// Calling Error() on a nil pointer may well panic
+			return TraceValue{Type: fmt.Sprintf("%T", v), Value: json.RawMessage("null")}, nil
+		}
+		data, jerr := json.Marshal(err.Error())
+		return TraceValue{Type: "error", Value: data}, jerr
+	}
+	if _, ok := v.(context.Context); ok {
+		return TraceValue{Type: traceContext, Value: json.RawMessage("null")}, nil
+	}
+
+	tv := TraceValue{Type: fmt.Sprintf("%T", v)}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		tv.Value = json.RawMessage("null")
+		return tv, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, err = json.Marshal(fmt.Sprintf("%v", v))
+	}
+	tv.Value = data
+	return tv, err
+}
+
+// ReadTrace reads a Trace previously serialised as JSON
+func ReadTrace(r io.Reader) (*Trace, error) {
+	var tr Trace
+	if err := json.NewDecoder(r).Decode(&tr); err != nil {
+		return nil, fmt.Errorf("failed to decode trace. %w", err)
+	}
+	return &tr, nil
+}
+
+func newTraceCall(enc ValueEncoder, name string, params, returns []any, passed bool) (TraceCall, error) {
+	var err error
+	tc := TraceCall{Method: name, Passed: passed}
+	if tc.Params, err = encodeValues(enc, params); err != nil {
+		return tc, fmt.Errorf("failed to encode parameters of %s. %w", name, err)
+	}
+	if tc.Returns, err = encodeValues(enc, returns); err != nil {
+		return tc, fmt.Errorf("failed to encode returns of %s. %w", name, err)
+	}
+	return tc, nil
+}
+
+func encodeValues(enc ValueEncoder, vals []any) ([]TraceValue, error) {
+	tvs := make([]TraceValue, len(vals))
+	for i, v := range vals {
+		tv, err := enc(v)
+		if err != nil {
+			return nil, fmt.Errorf("value %d. %w", i, err)
+		}
+		tvs[i] = tv
+	}
+	return tvs, nil
+}
+
+func (cr *callRecords) Trace(enc ValueEncoder) (*Trace, error) {
+	if enc == nil {
+		enc = DefaultValueEncoder
+	}
+	cr.Lock()
+	defer cr.Unlock()
+
+	tr := &Trace{
+		Expected: make([]TraceCall, 0, len(cr.calls)),
+		Calls:    make([]TraceCall, 0, len(cr.history)),
+	}
+	for _, call := range cr.calls {
+		tc, err := newTraceCall(enc, call.name, call.params, call.returns, call.passed)
+		if err != nil {
+			return nil, err
+		}
+		tc.Mock, tc.MockType = call.mock, call.mockType
+		tr.Expected = append(tr.Expected, tc)
+	}
+	for _, call := range cr.history {
+		tc, err := newTraceCall(enc, call.Name, call.Params, call.Returns, call.Passed)
+		if err != nil {
+			return nil, err
+		}
+		if call.Panicked {
+			tv, err := enc(call.Panic)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode panic from %s. %w", call.Name, err)
+			}
+			tc.Panic = &tv
+		}
+		tc.Mock, tc.MockType = call.Mock, call.MockType
+		tr.Calls = append(tr.Calls, tc)
+	}
+	return tr, nil
+}
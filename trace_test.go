@@ -0,0 +1,92 @@
+package ut
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTrace(t *testing.T) {
+	m := NewMockReader(t)
+	m.AddCall("Read", []byte("hat")).SetReturns(3, nil)
+	m.AddCall("Read", []byte("cat")).SetReturns(0, errors.New("no more"))
+
+	if n, err := m.Read([]byte("hat")); n != 3 || err != nil {
+		t.Fatalf("unexpected return %d, %v", n, err)
+	}
+
+	tr, err := m.Trace(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(tr); err != nil {
+		t.Fatal(err)
+	}
+	act, err := ReadTrace(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := &Trace{
+		Expected: []TraceCall{
+			{
+				Method:  "Read",
+				Params:  []TraceValue{{Type: "[]uint8", Value: json.RawMessage(`"aGF0"`)}},
+				Returns: []TraceValue{{Type: "int", Value: json.RawMessage(`3`)}, {Value: json.RawMessage(`null`)}},
+				Passed:  true,
+			},
+			{
+				Method:  "Read",
+				Params:  []TraceValue{{Type: "[]uint8", Value: json.RawMessage(`"Y2F0"`)}},
+				Returns: []TraceValue{{Type: "int", Value: json.RawMessage(`0`)}, {Type: "error", Value: json.RawMessage(`"no more"`)}},
+			},
+		},
+		Calls: []TraceCall{
+			{
+				Method:  "Read",
+				Params:  []TraceValue{{Type: "[]uint8", Value: json.RawMessage(`"aGF0"`)}},
+				Returns: []TraceValue{{Type: "int", Value: json.RawMessage(`3`)}, {Value: json.RawMessage(`null`)}},
+				Passed:  true,
+			},
+		},
+	}
+	if diff := cmp.Diff(exp, act); diff != "" {
+		t.Fatalf("trace not as expected. %s", diff)
+	}
+}
+
+func TestTraceNilError(t *testing.T) {
+	tv, err := DefaultValueEncoder((*formatErr)(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (TraceValue{Type: "*ut.formatErr", Value: json.RawMessage("null")}); !cmp.Equal(exp, tv) {
+		t.Fatalf("nil error not encoded as expected. %#v", tv)
+	}
+}
+
+func TestTraceEncoder(t *testing.T) {
+	m := NewMockReader(t)
+	m.RecordCall("Read", 1, nil)
+	m.Read([]byte("hat"))
+
+	tr, err := m.Trace(func(v any) (TraceValue, error) {
+		return TraceValue{Type: "x", Value: json.RawMessage(`"y"`)}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Calls) != 1 {
+		t.Fatalf("expected 1 call, have %d", len(tr.Calls))
+	}
+	for _, tv := range append(tr.Calls[0].Params, tr.Calls[0].Returns...) {
+		if tv.Type != "x" || string(tv.Value) != `"y"` {
+			t.Fatalf("encoder not used. %#v", tv)
+		}
+	}
+}
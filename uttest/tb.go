@@ -0,0 +1,220 @@
+// Package uttest helps test mocks, matchers and other test helpers. It
+// provides TB, a testing.TB that records failures rather than reporting
+// them, so a test can check that a mock fails when it should.
+//
+//	func TestMockFailsOnUnexpectedCall(t *testing.T) {
+//		tb := uttest.ExpectFailure(t, func(tb testing.TB) {
+//			m := NewMockFred(tb)
+//			m.doit("lemons")
+//		})
+//		if !tb.Contains("Unexpected call to doit") {
+//			t.Errorf("failure not as expected. %q %q", tb.Errors(), tb.Logs())
+//		}
+//	}
+package uttest
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TB is a testing.TB that records what is logged and whether the test has
+// failed, rather than passing them on to the test. FailNow, Fatal and SkipNow
+// stop the goroutine that calls them, as they do for a real test, so code
+// that uses a TB should be run via Run or ExpectFailure. Other methods, such
+// as Name and TempDir, are passed on to the test the TB was created for.
+type TB struct {
+	testing.TB
+	mu       sync.Mutex
+	logs     []string
+	errors   []string
+	failed   bool
+	stopped  bool
+	skipped  bool
+	cleanups []func()
+}
+
+// NewTB creates a TB for the test t
+func NewTB(t testing.TB) *TB {
+	return &TB{TB: t}
+}
+
+// Run calls fn with a new TB on a separate goroutine, so that fn can be
+// stopped by FailNow without stopping the test. It then runs any cleanups fn
+// registered and returns the TB so the test can examine what happened.
+func Run(t testing.TB, fn func(tb testing.TB)) *TB {
+	tb := NewTB(t)
+	tb.Go(func() { fn(tb) })
+	tb.RunCleanups()
+	return tb
+}
+
+// ExpectFailure is like Run, but fails t if fn does not fail the TB.
+func ExpectFailure(t testing.TB, fn func(tb testing.TB)) *TB {
+	t.Helper()
+	tb := Run(t, fn)
+	if !tb.Failed() {
+		t.Errorf("expected a failure, but there was none. Logs: %q", tb.Logs())
+	}
+	return tb
+}
+
+// ExpectSuccess is like Run, but fails t if fn fails the TB.
+func ExpectSuccess(t testing.TB, fn func(tb testing.TB)) *TB {
+	t.Helper()
+	tb := Run(t, fn)
+	if tb.Failed() {
+		t.Errorf("unexpected failure. Errors: %q, logs: %q", tb.Errors(), tb.Logs())
+	}
+	return tb
+}
+
+// Go calls fn on a new goroutine and waits for it to finish, whether it
+// returns or is stopped by FailNow.
+func (tb *TB) Go(fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	<-done
+}
+
+// RunCleanups runs the functions registered with Cleanup, most recently
+// registered first, as a test does when it ends. Each is run as Go runs
+// functions. They are then forgotten.
+func (tb *TB) RunCleanups() {
+	tb.mu.Lock()
+	cleanups := tb.cleanups
+	tb.cleanups = nil
+	tb.mu.Unlock()
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		tb.Go(cleanups[i])
+	}
+}
+
+// Logs returns the messages passed to Log and Logf
+func (tb *TB) Logs() []string {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return append([]string(nil), tb.logs...)
+}
+
+// Errors returns the messages passed to Error, Errorf, Fatal and Fatalf
+func (tb *TB) Errors() []string {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return append([]string(nil), tb.errors...)
+}
+
+// Contains reports whether any message logged or reported as an error
+// contains s
+func (tb *TB) Contains(s string) bool {
+	for _, l := range append(tb.Logs(), tb.Errors()...) {
+		if strings.Contains(l, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stopped reports whether FailNow, Fatal or Fatalf was called
+func (tb *TB) Stopped() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.stopped
+}
+
+func (tb *TB) Helper() {}
+
+func (tb *TB) Log(args ...any) {
+	tb.log(fmt.Sprintln(args...), false)
+}
+
+func (tb *TB) Logf(format string, args ...any) {
+	tb.log(fmt.Sprintf(format, args...), false)
+}
+
+func (tb *TB) Error(args ...any) {
+	tb.log(fmt.Sprintln(args...), true)
+}
+
+func (tb *TB) Errorf(format string, args ...any) {
+	tb.log(fmt.Sprintf(format, args...), true)
+}
+
+func (tb *TB) Fatal(args ...any) {
+	tb.Error(args...)
+	tb.FailNow()
+}
+
+func (tb *TB) Fatalf(format string, args ...any) {
+	tb.Errorf(format, args...)
+	tb.FailNow()
+}
+
+func (tb *TB) Fail() {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.failed = true
+}
+
+func (tb *TB) FailNow() {
+	tb.mu.Lock()
+	tb.failed = true
+	tb.stopped = true
+	tb.mu.Unlock()
+	runtime.Goexit()
+}
+
+func (tb *TB) Failed() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.failed
+}
+
+func (tb *TB) Skip(args ...any) {
+	tb.Log(args...)
+	tb.SkipNow()
+}
+
+func (tb *TB) Skipf(format string, args ...any) {
+	tb.Logf(format, args...)
+	tb.SkipNow()
+}
+
+func (tb *TB) SkipNow() {
+	tb.mu.Lock()
+	tb.skipped = true
+	tb.mu.Unlock()
+	runtime.Goexit()
+}
+
+func (tb *TB) Skipped() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.skipped
+}
+
+func (tb *TB) Cleanup(fn func()) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.cleanups = append(tb.cleanups, fn)
+}
+
+// log records a message. Like the testing package we drop the newline
+// Sprintln adds.
+func (tb *TB) log(msg string, isError bool) {
+	msg = strings.TrimSuffix(msg, "\n")
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if !isError {
+		tb.logs = append(tb.logs, msg)
+		return
+	}
+	tb.errors = append(tb.errors, msg)
+	tb.failed = true
+}
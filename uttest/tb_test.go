@@ -0,0 +1,80 @@
+package uttest
+
+import (
+	"testing"
+
+	"github.com/philpearl/ut"
+)
+
+type mockGetter struct {
+	ut.CallTracker
+}
+
+func (m *mockGetter) Get(key string) (string, error) {
+	r := m.TrackCall("Get", key)
+	if len(r) == 0 {
+		return "", nil
+	}
+	return r[0].(string), ut.NilOrError(r[1])
+}
+
+func TestExpectFailure(t *testing.T) {
+	tb := ExpectFailure(t, func(tb testing.TB) {
+		m := &mockGetter{ut.NewCallRecords(tb)}
+		m.Get("b")
+		t.Error("FailNow should have stopped the function")
+	})
+	if !tb.Stopped() {
+		t.Error("expected the TB to be stopped")
+	}
+	if !tb.Contains(`Unexpected call to Get("b")`) {
+		t.Errorf("failure not as expected. %q %q", tb.Errors(), tb.Logs())
+	}
+}
+
+func TestExpectSuccess(t *testing.T) {
+	tb := ExpectSuccess(t, func(tb testing.TB) {
+		m := &mockGetter{ut.NewCallRecords(tb)}
+		m.AddCall("Get", "a").SetReturns("apple", nil)
+		m.Get("a")
+		m.AssertDone()
+	})
+	if tb.Stopped() || len(tb.Errors()) != 0 {
+		t.Errorf("unexpected failure. %q", tb.Errors())
+	}
+}
+
+func TestCleanups(t *testing.T) {
+	// The missed call is only reported as the test ends, by the cleanup Sub
+	// registers
+	tb := Run(t, func(tb testing.TB) {
+		m := &mockGetter{ut.NewCallRecords(tb)}
+		sub := &mockGetter{m.Sub(tb)}
+		sub.AddCall("Get", "a")
+		if tb.Failed() {
+			t.Error("expected no failure before the cleanups run")
+		}
+	})
+	if !tb.Failed() || tb.Stopped() {
+		t.Errorf("expected the cleanup to fail the TB without stopping it. %q", tb.Errors())
+	}
+}
+
+func TestLogs(t *testing.T) {
+	tb := Run(t, func(tb testing.TB) {
+		tb.Log("hat", 1)
+		tb.Logf("hat %d", 2)
+		tb.Error("coat")
+		tb.Skip("skipping")
+		t.Error("Skip should have stopped the function")
+	})
+	if logs := tb.Logs(); len(logs) != 3 || logs[0] != "hat 1" || logs[1] != "hat 2" || logs[2] != "skipping" {
+		t.Errorf("logs not as expected. %q", logs)
+	}
+	if errs := tb.Errors(); len(errs) != 1 || errs[0] != "coat" {
+		t.Errorf("errors not as expected. %q", errs)
+	}
+	if !tb.Failed() || !tb.Skipped() || tb.Stopped() {
+		t.Errorf("unexpected state: failed %t, skipped %t, stopped %t", tb.Failed(), tb.Skipped(), tb.Stopped())
+	}
+}
@@ -0,0 +1,222 @@
+package ut
+
+// Verifier checks the calls made to a mock after the fact, for tests written
+// in the arrange-act-assert style. Create one with Verify once the code under
+// test has run. Failures are reported to the test without stopping it, as
+// AssertDone does.
+//
+// Parameters are given as they are to AddCall, so may be Matchers. Giving no
+// parameters matches a call to the method with any parameters.
+type Verifier struct {
+	cr *callRecords
+	// calls are the calls made to the mock when the Verifier was created.
+	// verified marks those a check has accounted for.
+	calls    []Call
+	verified []bool
+	// pending is the last Called() check, until Times(), AtLeast() or
+	// AtMost() follows it
+	pending *CalledVerifier
+}
+
+// Verify returns a Verifier for the calls made so far to mock, which may be a
+// tracker created by NewCallRecords or a mock that contains one. Create the
+// mock with the Recording() option so it accepts calls without expectations
+// being added first.
+//
+//	m := NewMockStore(t, ut.Recording())
+//	Save(m, records)
+//	ut.Verify(m).Called("Save", ut.AnyContext(), recordA).Times(2).NotCalled("Delete").NoMoreInteractions()
+func Verify(mock CallTracker) *Verifier {
+	cr, ok := trackerOf(mock)
+	if !ok {
+		panic("Verify: mock does not contain a tracker created by NewCallRecords")
+	}
+	calls := cr.Calls()
+	v := &Verifier{cr: cr, calls: calls, verified: make([]bool, len(calls))}
+	// A Called() at the end of the chain is checked when the test ends
+	cr.t.Cleanup(v.flush)
+	return v
+}
+
+// Called checks the calls made to the named method with parameters matching
+// params, or with any parameters if there are no params. Follow it with
+// Times(), AtLeast() or AtMost() to check the number of calls. Otherwise it
+// checks there was at least one call, when the next check starts or, if there
+// is none, when the test ends.
+func (v *Verifier) Called(name string, params ...any) *CalledVerifier {
+	v.cr.t.Helper()
+	v.flush()
+	cv := &CalledVerifier{Verifier: v, name: name, params: params}
+	for i, c := range v.calls {
+		if v.matches(c, name, params) {
+			cv.count++
+			v.verified[i] = true
+		}
+	}
+	v.pending = cv
+	return cv
+}
+
+// NotCalled checks that no call was made to the named method with parameters
+// matching params. With no params it checks there were no calls to the method
+// at all.
+func (v *Verifier) NotCalled(name string, params ...any) *Verifier {
+	v.cr.t.Helper()
+	v.flush()
+	for _, c := range v.calls {
+		if !v.matches(c, name, params) {
+			continue
+		}
+		v.fail("Expected no call to %s%s, but got %s%s", name, v.cr.formatter.FormatList(params), c.Name, v.cr.formatter.FormatList(c.Params))
+	}
+	return v
+}
+
+// CalledInOrder checks that calls matching each of calls were made in the
+// order given. Other calls may come between them. Only the Name and Params of
+// each Call are used, and a Call with no Params matches any parameters.
+//
+//	v.CalledInOrder(ut.Call{Name: "Begin"}, ut.Call{Name: "Commit"})
+func (v *Verifier) CalledInOrder(calls ...Call) *Verifier {
+	v.cr.t.Helper()
+	v.flush()
+	next := 0
+	matched := make([]int, 0, len(calls))
+	for i, c := range v.calls {
+		if next < len(calls) && v.matches(c, calls[next].Name, calls[next].Params) {
+			matched = append(matched, i)
+			next++
+		}
+	}
+	if next < len(calls) {
+		missing := calls[next]
+		if next == 0 {
+			v.fail("Expected calls in order, but there was no call to %s%s", missing.Name, v.cr.formatter.FormatList(missing.Params))
+		} else {
+			prev := calls[next-1]
+			v.fail("Expected calls in order, but no call to %s%s followed %s%s", missing.Name, v.cr.formatter.FormatList(missing.Params), prev.Name, v.cr.formatter.FormatList(prev.Params))
+		}
+		v.showCalls()
+		return v
+	}
+	for _, i := range matched {
+		v.verified[i] = true
+	}
+	return v
+}
+
+// NoMoreInteractions checks that every call made to the mock has been
+// accounted for by Called() or CalledInOrder().
+func (v *Verifier) NoMoreInteractions() *Verifier {
+	v.cr.t.Helper()
+	v.flush()
+	for i, c := range v.calls {
+		if !v.verified[i] {
+			v.fail("Unverified call to %s%s", c.Name, v.cr.formatter.FormatList(c.Params))
+		}
+	}
+	return v
+}
+
+// CalledVerifier is returned by Verifier.Called() to check how many matching
+// calls were made. It embeds the Verifier so checks can be chained.
+type CalledVerifier struct {
+	*Verifier
+	name   string
+	params []any
+	count  int
+}
+
+// Times checks that exactly n matching calls were made
+func (cv *CalledVerifier) Times(n int) *Verifier {
+	cv.cr.t.Helper()
+	cv.settle()
+	if cv.count != n {
+		cv.fail("Expected %d calls to %s%s, but there were %d", n, cv.name, cv.cr.formatter.FormatList(cv.params), cv.count)
+		cv.showCallsTo(cv.name)
+	}
+	return cv.Verifier
+}
+
+// AtLeast checks that at least n matching calls were made
+func (cv *CalledVerifier) AtLeast(n int) *Verifier {
+	cv.cr.t.Helper()
+	cv.settle()
+	if cv.count < n {
+		cv.fail("Expected at least %d calls to %s%s, but there were %d", n, cv.name, cv.cr.formatter.FormatList(cv.params), cv.count)
+		cv.showCallsTo(cv.name)
+	}
+	return cv.Verifier
+}
+
+// AtMost checks that no more than n matching calls were made
+func (cv *CalledVerifier) AtMost(n int) *Verifier {
+	cv.cr.t.Helper()
+	cv.settle()
+	if cv.count > n {
+		cv.fail("Expected at most %d calls to %s%s, but there were %d", n, cv.name, cv.cr.formatter.FormatList(cv.params), cv.count)
+		cv.showCallsTo(cv.name)
+	}
+	return cv.Verifier
+}
+
+// settle stops Called() checking there was at least one call, as the number
+// of calls is to be checked instead
+func (cv *CalledVerifier) settle() {
+	if cv.pending == cv {
+		cv.pending = nil
+	}
+}
+
+// flush checks there was at least one call for a pending Called()
+func (v *Verifier) flush() {
+	v.cr.t.Helper()
+	cv := v.pending
+	if cv == nil {
+		return
+	}
+	v.pending = nil
+	if cv.count == 0 {
+		v.fail("Expected a call to %s%s, but there were none", cv.name, v.cr.formatter.FormatList(cv.params))
+		v.showCallsTo(cv.name)
+	}
+}
+
+// matches reports whether the call c is to the named method with parameters
+// matching params. If there are no params any parameters match.
+func (v *Verifier) matches(c Call, name string, params []any) bool {
+	name = v.cr.qualify(name)
+	if c.Name != name {
+		return false
+	}
+	if len(params) == 0 {
+		return true
+	}
+	v.cr.Lock()
+	defer v.cr.Unlock()
+	return v.cr.paramsMatch(&callRecord{name: name, params: params}, c.Params)
+}
+
+func (v *Verifier) fail(format string, args ...any) {
+	v.cr.t.Helper()
+	v.cr.errorf(format, args...)
+}
+
+// showCallsTo logs the calls made to the named method
+func (v *Verifier) showCallsTo(name string) {
+	v.cr.t.Helper()
+	name = v.cr.qualify(name)
+	for _, c := range v.calls {
+		if c.Name == name {
+			v.cr.logf("  got %s%s", c.Name, v.cr.formatter.FormatList(c.Params))
+		}
+	}
+}
+
+// showCalls logs all the calls made to the mock
+func (v *Verifier) showCalls() {
+	v.cr.t.Helper()
+	for _, c := range v.calls {
+		v.cr.logf("  got %s%s", c.Name, v.cr.formatter.FormatList(c.Params))
+	}
+}
@@ -0,0 +1,121 @@
+package ut
+
+import (
+	"testing"
+
+	"github.com/philpearl/ut/uttest"
+)
+
+func TestVerify(t *testing.T) {
+	getter := &MockGetter{NewCallRecords(t, Recording())}
+	getter.SetDefault("Get", "", nil)
+
+	getter.Get("a")
+	getter.Get("b")
+	getter.Get("a")
+
+	Verify(getter).
+		Called("Get", "a").Times(2).
+		Called("Get", matcher{desc: "not a", match: func(actual any) bool { return actual != "a" }}).AtLeast(1).
+		NotCalled("Get", "c").
+		NotCalled("Put").
+		Called("Put").Times(0).
+		Called("Put").AtMost(1).
+		Called("Get").Times(3).
+		CalledInOrder(Call{Name: "Get", Params: []any{"a"}}, Call{Name: "Get", Params: []any{"b"}}).
+		NoMoreInteractions()
+}
+
+func TestVerifyFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		verify func(v *Verifier)
+		error  string
+	}{
+		{
+			name:   "not called",
+			verify: func(v *Verifier) { v.Called("Get", "c") },
+			error:  `Expected a call to Get("c"), but there were none`,
+		},
+		{
+			name:   "not called before another check",
+			verify: func(v *Verifier) { v.Called("Get", "c").NotCalled("Put") },
+			error:  `Expected a call to Get("c"), but there were none`,
+		},
+		{
+			name:   "times none",
+			verify: func(v *Verifier) { v.Called("Get", "c").Times(1) },
+			error:  `Expected 1 calls to Get("c"), but there were 0`,
+		},
+		{
+			name:   "times",
+			verify: func(v *Verifier) { v.Called("Get", "a").Times(1) },
+			error:  `Expected 1 calls to Get("a"), but there were 2`,
+		},
+		{
+			name:   "at least",
+			verify: func(v *Verifier) { v.Called("Get", "b").AtLeast(2) },
+			error:  `Expected at least 2 calls to Get("b"), but there were 1`,
+		},
+		{
+			name:   "at most",
+			verify: func(v *Verifier) { v.Called("Get", "a").AtMost(1) },
+			error:  `Expected at most 1 calls to Get("a"), but there were 2`,
+		},
+		{
+			name:   "called",
+			verify: func(v *Verifier) { v.NotCalled("Get", "b") },
+			error:  `Expected no call to Get("b"), but got Get("b")`,
+		},
+		{
+			name: "order",
+			verify: func(v *Verifier) {
+				v.CalledInOrder(Call{Name: "Get", Params: []any{"b"}}, Call{Name: "Get", Params: []any{"b"}})
+			},
+			error: `Expected calls in order, but no call to Get("b") followed Get("b")`,
+		},
+		{
+			name:   "any parameters",
+			verify: func(v *Verifier) { v.Called("Get").Times(2) },
+			error:  `Expected 2 calls to Get(), but there were 3`,
+		},
+		{
+			name:   "no more",
+			verify: func(v *Verifier) { v.Called("Get", "a").Times(2).NoMoreInteractions() },
+			error:  `Unverified call to Get("b")`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ft := uttest.NewTB(t)
+			getter := &MockGetter{NewCallRecords(ft, Recording())}
+			getter.SetDefault("Get", "", nil)
+			getter.Get("a")
+			getter.Get("b")
+			getter.Get("a")
+
+			test.verify(Verify(getter))
+			ft.RunCleanups()
+			if len(ft.Errors()) != 1 || ft.Errors()[0] != test.error {
+				t.Fatalf("errors not as expected. %q", ft.Errors())
+			}
+		})
+	}
+}
+
+func TestVerifyShared(t *testing.T) {
+	shared := NewCallRecords(t, Recording())
+	getter := &MockGetter{NewCallRecords(t, WithName("getter"), WithTracker(shared))}
+	other := &MockGetter{NewCallRecords(t, WithName("other"), WithTracker(shared))}
+	shared.SetDefault("getter.Get", "", nil)
+	shared.SetDefault("other.Get", "", nil)
+
+	getter.Get("a")
+	other.Get("b")
+
+	Verify(getter).Called("Get", "a").Times(1).NoMoreInteractions()
+	if calls := shared.Calls(); len(calls) != 2 {
+		t.Fatalf("expected the shared tracker to have both calls. %#v", calls)
+	}
+}